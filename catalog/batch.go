@@ -0,0 +1,145 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package catalog
+
+import (
+	"context"
+
+	"github.com/dnaeon/gru/resource"
+)
+
+// batchKey returns the batch key of a resource or an
+// empty string if the resource cannot be processed in batches.
+// Resources with a timeout or retries are processed on their
+// own, so that the timeout and retries apply only to them.
+func batchKey(r resource.Resource) string {
+	if r.ProcessingTimeout() > 0 || r.RetryPolicy().Retries > 0 {
+		return ""
+	}
+
+	if b, ok := r.(resource.Batcher); ok {
+		return b.BatchKey()
+	}
+
+	return ""
+}
+
+// takeBatch removes the resources with the given batch key
+// from the list of ready resources and returns them.
+func takeBatch(key string, ready []resource.Resource) (batch, remaining []resource.Resource) {
+	remaining = ready[:0]
+	for _, r := range ready {
+		if batchKey(r) == key {
+			batch = append(batch, r)
+		} else {
+			remaining = append(remaining, r)
+		}
+	}
+
+	return batch, remaining
+}
+
+// executeBatch processes resources with the same batch key.
+// The resources are evaluated using a single operation, and the
+// resources which should be created or deleted are processed in
+// a single operation as well. If an operation on the batch fails,
+// the resources are processed one by one instead, so that the
+// status of each resource is reported.
+func (c *Catalog) executeBatch(ctx context.Context, resources []resource.Resource) []*StatusItem {
+	batch := resources[0].(resource.Batcher).NewBatch(resources)
+	defer batch.Close()
+
+	if err := batch.Load(ctx); err != nil {
+		c.config.Logger.Printf("Unable to load batch of %d resources: %s\n", len(resources), err)
+		batch.Close()
+	}
+
+	executions := make([]*execution, 0, len(resources))
+	for _, r := range resources {
+		executions = append(executions, c.begin(ctx, r))
+	}
+
+	if !c.config.DryRun {
+		actions := []struct {
+			event string
+			take  func(context.Context, []resource.Resource) error
+		}{
+			{EventCreated, batch.Create},
+			{EventDeleted, batch.Delete},
+		}
+
+		changed, failed := false, false
+		for _, action := range actions {
+			var pending []*execution
+			var targets []resource.Resource
+			for _, e := range executions {
+				if !e.finished && e.action != nil && e.event == action.event {
+					pending = append(pending, e)
+					targets = append(targets, e.r)
+				}
+			}
+
+			if len(pending) == 0 {
+				continue
+			}
+
+			changed = true
+			if err := action.take(ctx, targets); err != nil {
+				// Process the resources one by one
+				c.config.Logger.Printf("Batch of %d resources failed: %s\n", len(pending), err)
+				failed = true
+				continue
+			}
+
+			for _, e := range pending {
+				c.acted(e)
+			}
+		}
+
+		// Reload the batch so that properties are evaluated
+		// against the current state. If the resources are processed
+		// one by one, then they are evaluated one by one as well.
+		switch {
+		case failed:
+			batch.Close()
+		case changed:
+			if err := batch.Load(ctx); err != nil {
+				c.config.Logger.Printf("Unable to reload batch of %d resources: %s\n", len(resources), err)
+				batch.Close()
+			}
+		}
+	}
+
+	items := make([]*StatusItem, 0, len(executions))
+	for _, e := range executions {
+		c.act(e)
+		c.complete(e)
+		c.end(e)
+		items = append(items, e.item)
+	}
+
+	return items
+}
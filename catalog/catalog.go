@@ -26,9 +26,14 @@
 package catalog
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/dnaeon/gru/graph"
 	"github.com/dnaeon/gru/resource"
@@ -42,22 +47,59 @@ type Catalog struct {
 	// Unsorted contains the list of resources created by Lua
 	Unsorted []resource.Resource `luar:"-"`
 
+	// Purge specifies whether resources, which were managed by
+	// the module during it's last successful run, but have been
+	// removed from the module since then should be purged.
+	// Purging requires a local state file to be configured.
+	Purge bool `luar:"purge"`
+
+	// Module is the name of the module, which is used as
+	// the key for the module in the local state file
+	module string `luar:"-"`
+
+	// Purged contains the ids of the resources,
+	// which have been removed from the module
+	purged map[string]bool `luar:"-"`
+
 	// Collection contains the unsorted resources as a collection
 	collection resource.Collection `luar:"-"`
 
 	// Sorted contains the resources after a topological sort.
 	sorted []*graph.Node `luar:"-"`
 
+	// Graph contains the resource dependency graph. It is used for
+	// finding the dependencies of resources.
+	graph *graph.Graph `luar:"-"`
+
 	// Reversed contains the resource dependency graph in reverse
 	// order. It is used for finding the reverse dependencies of
 	// resources.
 	reversed *graph.Graph `luar:"-"`
 
+	// NotifiedBy maps resource ids to the ids of the resources,
+	// which notify them when they have changed.
+	notifiedBy map[string][]string `luar:"-"`
+
+	// Dependencies maps resource ids to the ids of the resources
+	// they depend on, including the ones declared by using the
+	// before and required_by relationships.
+	dependencies map[string][]string `luar:"-"`
+
+	// Selected contains the ids of the resources, which
+	// are to be processed. If nil all resources are processed.
+	selected map[string]bool `luar:"-"`
+
 	// Status contains status information about resources
 	status *Status `luar:"-"`
 
 	// Configuration settings
 	config *Config `luar:"-"`
+
+	// Serializes the delivery of events to observers
+	observerMu sync.Mutex `luar:"-"`
+
+	// Executes Lua code while processing resources
+	lua *luaExecutor `luar:"-"`
 }
 
 // Config type represents a set of settings to use when
@@ -66,6 +108,15 @@ type Config struct {
 	// Name of the Lua module to load and execute
 	Module string
 
+	// Path to a compiled catalog, which is loaded
+	// instead of the Lua module, if set
+	Catalog string
+
+	// Path to the local state file, which contains the resources
+	// managed during the last successful run of each module.
+	// If empty, no state is persisted.
+	StateFile string
+
 	// Do not take any actions, just report what would be done
 	DryRun bool
 
@@ -80,56 +131,259 @@ type Config struct {
 
 	// Number of goroutines to use for concurrent processing
 	Concurrency int
+
+	// Timeout is the maximum amount of time processing of the
+	// catalog may take. Resources, which have not been processed
+	// when the timeout expires are skipped.
+	// A zero value means that there is no timeout.
+	Timeout time.Duration
+
+	// Tags specifies that only resources with any of the given
+	// tags and their dependencies should be processed.
+	Tags []string
+
+	// SkipTags specifies that resources with any of the
+	// given tags should not be processed.
+	SkipTags []string
+
+	// Only specifies the ids of the resources, which should be
+	// processed along with their dependencies.
+	Only []string
+
+	// FailurePolicy specifies how to proceed when processing of a
+	// resource fails. Defaults to FailurePolicyContinue.
+	FailurePolicy string
+
+	// Observers receive the events emitted during
+	// loading and processing of the catalog
+	Observers []Observer
 }
 
 // Status type contains status information about processed resources.
 type Status struct {
 	sync.RWMutex
 
+	// DryRun specifies whether the status was produced by a
+	// dry run, in which case no changes were actually made.
+	DryRun bool
+
 	// Items contain the status for resources after being processed.
 	Items map[string]*StatusItem
 }
 
 // StatusItem type represents a single item for a processed resource.
 type StatusItem struct {
+	// ID is the unique id of the processed resource.
+	ID string `json:"id"`
+
 	// StateChanged field specifies whether or not a resource has changed
 	// after being evaluated and processed.
-	StateChanged bool
+	StateChanged bool `json:"stateChanged"`
+
+	// StateBefore is the state of the resource as evaluated
+	// prior processing it.
+	StateBefore string `json:"stateBefore"`
+
+	// StateAfter is the state of the resource after processing it.
+	StateAfter string `json:"stateAfter"`
+
+	// Transition is the action taken in order to bring the resource
+	// into the desired state, e.g. create or delete.
+	// It is empty if no such action was needed.
+	Transition string `json:"transition,omitempty"`
+
+	// Properties contains the resource properties,
+	// which were out of date.
+	Properties []PropertyChange `json:"properties,omitempty"`
+
+	// Triggers contains the resource ids of the monitored
+	// resources, for which triggers were executed.
+	Triggers []string `json:"triggers,omitempty"`
+
+	// Start is the time when processing of the resource started.
+	Start time.Time `json:"start"`
+
+	// End is the time when processing of the resource finished.
+	End time.Time `json:"end"`
+
+	// Duration is the time it took to process the resource.
+	Duration time.Duration `json:"duration"`
+
+	// Skipped specifies whether the resource was not processed,
+	// e.g. because some of it's dependencies have failed or
+	// because of it's only_if and unless guards.
+	Skipped bool `json:"skipped"`
+
+	// Retries is the number of times failed operations
+	// on the resource have been retried.
+	Retries int `json:"retries"`
+
+	// Refreshed specifies whether the resource was refreshed,
+	// because some of the resources notifying it have changed.
+	Refreshed bool `json:"refreshed"`
 
 	// Err contains any errors that were encountered during resource
 	// evaluation and processing.
-	Err error
+	Err error `json:"-"`
 }
 
-// Summary displays a summary of the resource status.
-func (s *Status) Summary(l *log.Logger) {
+// PropertyChange type describes a resource property, which was out of date.
+type PropertyChange struct {
+	// Name of the property
+	Name string `json:"name"`
+
+	// Old is the value of the property before it was set
+	Old interface{} `json:"old"`
+
+	// New is the desired value of the property
+	New interface{} `json:"new"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (si *StatusItem) MarshalJSON() ([]byte, error) {
+	type item StatusItem
+
+	var msg string
+	if si.Err != nil {
+		msg = si.Err.Error()
+	}
+
+	v := &struct {
+		*item
+		Error string `json:"error,omitempty"`
+	}{
+		item:  (*item)(si),
+		Error: msg,
+	}
+
+	return json.Marshal(v)
+}
+
+// finish marks the item as processed.
+func (si *StatusItem) finish() {
+	si.End = time.Now()
+	si.Duration = si.End.Sub(si.Start)
+}
+
+// Resource transitions
+const (
+	// TransitionCreate is used when a resource is being created
+	TransitionCreate = "create"
+
+	// TransitionDelete is used when a resource is being deleted
+	TransitionDelete = "delete"
+)
+
+// errStopped is used for resources, which have not been
+// processed, because of an earlier failure when using
+// the FailurePolicyFailFast failure policy.
+var errStopped = errors.New("processing stopped after a previous failure")
+
+// Failure policies
+const (
+	// FailurePolicyContinue keeps processing resources after a
+	// failure and skips only the direct dependents of the failed
+	// resource. This is the default failure policy.
+	FailurePolicyContinue = "continue"
+
+	// FailurePolicyFailFast stops scheduling new resources
+	// after the first failure.
+	FailurePolicyFailFast = "fail-fast"
+
+	// FailurePolicyFailSubtree keeps processing resources after
+	// a failure and skips all transitive dependents of the
+	// failed resource.
+	FailurePolicyFailSubtree = "fail-subtree"
+)
+
+// Report type contains the results of processing the catalog.
+// A report can be serialized to JSON.
+type Report struct {
+	// DryRun specifies whether the report was produced by a
+	// dry run, in which case changed resources are the ones,
+	// which would have been changed.
+	DryRun bool `json:"dryRun"`
+
+	// UpToDate is the number of resources, which were up-to-date.
+	UpToDate int `json:"upToDate"`
+
+	// Changed is the number of resources, which have changed.
+	Changed int `json:"changed"`
+
+	// Failed is the number of resources, which have failed.
+	Failed int `json:"failed"`
+
+	// Skipped is the number of resources, which were skipped.
+	Skipped int `json:"skipped"`
+
+	// Items contains the status of each processed resource,
+	// ordered by the time processing of the resource has started.
+	Items []*StatusItem `json:"items"`
+}
+
+// byStart implements sort.Interface for sorting
+// status items by their start time.
+type byStart []*StatusItem
+
+func (s byStart) Len() int           { return len(s) }
+func (s byStart) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byStart) Less(i, j int) bool { return s[i].Start.Before(s[j].Start) }
+
+// Report creates a report from the resource status.
+func (s *Status) Report() *Report {
 	s.Lock()
 	defer s.Unlock()
 
-	var changed, failed, uptodate int
+	report := &Report{
+		DryRun: s.DryRun,
+		Items:  make([]*StatusItem, 0, len(s.Items)),
+	}
+
 	for _, item := range s.Items {
 		switch {
+		case item.Skipped:
+			report.Skipped++
 		case item.StateChanged == true && item.Err == nil:
-			changed++
+			report.Changed++
 		case item.StateChanged == false && item.Err == nil:
-			uptodate++
+			report.UpToDate++
 		default:
-			failed++
+			report.Failed++
 		}
+		report.Items = append(report.Items, item)
+	}
+	sort.Sort(byStart(report.Items))
+
+	return report
+}
+
+// Summary displays a summary of the resource status.
+func (s *Status) Summary(l *log.Logger) {
+	r := s.Report()
+
+	if r.DryRun {
+		l.Printf("%d up-to-date, %d would change, %d failed, %d skipped\n", r.UpToDate, r.Changed, r.Failed, r.Skipped)
+		return
 	}
 
-	l.Printf("%d up-to-date, %d changed, %d failed\n", uptodate, changed, failed)
+	l.Printf("%d up-to-date, %d changed, %d failed, %d skipped\n", r.UpToDate, r.Changed, r.Failed, r.Skipped)
 }
 
 // New creates a new empty catalog with the provided configuration
 func New(config *Config) *Catalog {
 	c := &Catalog{
-		config:     config,
-		collection: make(resource.Collection),
-		sorted:     make([]*graph.Node, 0),
-		reversed:   graph.New(),
+		config:       config,
+		collection:   make(resource.Collection),
+		sorted:       make([]*graph.Node, 0),
+		graph:        graph.New(),
+		reversed:     graph.New(),
+		notifiedBy:   make(map[string][]string),
+		dependencies: make(map[string][]string),
+		purged:       make(map[string]bool),
+		module:       config.Module,
 		status: &Status{
-			Items: make(map[string]*StatusItem),
+			DryRun: config.DryRun,
+			Items:  make(map[string]*StatusItem),
 		},
 		Unsorted: make([]resource.Resource, 0),
 	}
@@ -162,10 +416,30 @@ func (c *Catalog) Add(resources ...resource.Resource) {
 
 // Load loads resources into the catalog
 func (c *Catalog) Load() error {
-	// Register the resource providers and catalog in Lua
-	resource.LuaRegisterBuiltin(c.config.L)
-	if err := c.config.L.DoFile(c.config.Module); err != nil {
-		return err
+	switch c.config.FailurePolicy {
+	case "", FailurePolicyContinue, FailurePolicyFailFast, FailurePolicyFailSubtree:
+		break
+	default:
+		return fmt.Errorf("Unknown failure policy '%s'", c.config.FailurePolicy)
+	}
+
+	if c.config.Catalog != "" {
+		// Rebuild the resources from a compiled catalog
+		if err := c.loadCompiled(c.config.Catalog); err != nil {
+			return err
+		}
+	} else {
+		// Register the resource providers and catalog in Lua
+		resource.LuaRegisterBuiltin(c.config.L)
+		if err := c.config.L.DoFile(c.config.Module); err != nil {
+			return err
+		}
+	}
+
+	if c.Purge && c.config.StateFile != "" {
+		if err := c.purgeRemoved(); err != nil {
+			return err
+		}
 	}
 
 	// Perform a topological sort of the resources
@@ -179,134 +453,424 @@ func (c *Catalog) Load() error {
 		return err
 	}
 
-	reversed := collectionGraph.Reversed()
+	// Resources which are notified by others must be refreshable
+	for _, r := range c.Unsorted {
+		for _, id := range r.Notifies() {
+			if _, ok := collection[id].(resource.Refresher); !ok {
+				return fmt.Errorf("%s notifies %s, which cannot be refreshed", r.ID(), id)
+			}
+			c.notifiedBy[id] = append(c.notifiedBy[id], r.ID())
+		}
+	}
 
 	sorted, err := collectionGraph.Sort()
+	if err == graph.ErrCircularDependency {
+		// Report the actual cycles instead of the remaining nodes
+		if cycleErr := collectionGraph.CheckCycles(); cycleErr != nil {
+			return cycleErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	deps := collection.Dependencies()
+	selected, err := selectResources(collection, deps, c.config)
 	if err != nil {
 		return err
 	}
 
 	// Set catalog fields
 	c.collection = collection
+	c.selected = selected
+	c.dependencies = deps
 	c.sorted = sorted
-	c.reversed = reversed
+	c.graph = collectionGraph
+	c.reversed = collectionGraph.Reversed()
 
 	c.config.Logger.Printf("Loaded %d resources\n", len(c.sorted))
+	for _, node := range c.sorted {
+		c.emit(&Event{Type: EventLoaded, ID: node.Name})
+	}
+
+	if c.selected != nil {
+		c.config.Logger.Printf("Selected %d resources for processing\n", len(c.selected))
+	}
 
 	return nil
 }
 
-// Run processes the resources from catalog
+// Run processes the resources from catalog.
 func (c *Catalog) Run() *Status {
-	// process executes a single resource
-	process := func(r resource.Resource) {
-		id := r.ID()
-		item := c.execute(r)
-		c.status.Lock()
-		defer c.status.Unlock()
-		c.status.Items[id] = item
-		if item.Err != nil {
-			c.config.Logger.Printf("%s %s\n", id, item.Err)
-		}
+	return c.RunContext(context.Background())
+}
+
+// RunContext processes the resources from catalog using the given context.
+// When the context is done, resources which have not been processed yet
+// are skipped.
+//
+// A resource is scheduled for processing as soon as all of it's
+// dependencies have been processed. The number of resources being
+// processed at the same time is bounded by the configured concurrency.
+// Resources which are not concurrent are processed one at a time.
+// Ready resources with the same batch key are processed together
+// as a single batch, e.g. packages installed in a single transaction.
+func (c *Catalog) RunContext(ctx context.Context) *Status {
+	if c.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+		defer cancel()
 	}
 
-	// Start goroutines for concurrent processing
-	var wg sync.WaitGroup
-	ch := make(chan resource.Resource, 1024)
-	c.config.Logger.Printf("Starting %d goroutines for concurrent processing\n", c.config.Concurrency)
-	for i := 0; i < c.config.Concurrency; i++ {
-		wg.Add(1)
-		worker := func() {
-			defer wg.Done()
-			for r := range ch {
-				c.config.Logger.Printf("%s is concurrent", r.ID())
-				process(r)
-			}
+	c.lua = newLuaExecutor(c.config.L)
+	defer c.lua.Close()
+
+	concurrency := c.config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// Number of dependencies for each resource,
+	// which have not been processed yet
+	pending := make(map[string]int)
+	for id, node := range c.graph.Nodes {
+		pending[id] = len(node.Edges)
+	}
+
+	// Resources which are ready to be processed
+	var ready, readySerial []resource.Resource
+	enqueue := func(r resource.Resource) {
+		if r.IsConcurrent() {
+			ready = append(ready, r)
+		} else {
+			readySerial = append(readySerial, r)
 		}
-		go worker()
 	}
 
-	// Process the resources
 	for _, node := range c.sorted {
-		r := c.collection[node.Name]
+		if pending[node.Name] == 0 {
+			enqueue(c.collection[node.Name])
+		}
+	}
+
+	// Processed resources are sent back over this channel
+	done := make(chan []resource.Resource)
+
+	// process executes the given resources. Multiple resources
+	// are processed as a batch.
+	process := func(resources []resource.Resource, stopped bool) {
+		var selected []resource.Resource
+		for _, r := range resources {
+			if c.isSelected(r.ID()) {
+				selected = append(selected, r)
+			}
+		}
+
+		var items []*StatusItem
 		switch {
-		// Resource is concurrent and is an isolated node
-		case r.IsConcurrent() && len(r.Dependencies()) == 0 && len(c.reversed.Nodes[r.ID()].Edges) == 0:
-			ch <- r
-			continue
-		// Resource is concurrent and has no reverse dependencies
-		case r.IsConcurrent() && len(c.reversed.Nodes[r.ID()].Edges) == 0:
-			ch <- r
-			continue
-		// Resource is not concurrent
+		case len(selected) == 0:
+		case stopped:
+			for _, r := range selected {
+				item := &StatusItem{
+					ID:      r.ID(),
+					Start:   time.Now(),
+					Skipped: true,
+					Err:     errStopped,
+				}
+				item.finish()
+				items = append(items, item)
+			}
+		case len(selected) == 1:
+			c.emit(&Event{Type: EventScheduled, ID: selected[0].ID()})
+			items = append(items, c.execute(ctx, selected[0]))
 		default:
-			process(r)
+			for _, r := range selected {
+				c.emit(&Event{Type: EventScheduled, ID: r.ID()})
+			}
+			items = c.executeBatch(ctx, selected)
+		}
+
+		for i, r := range selected {
+			c.record(r, items[i])
+		}
+
+		done <- resources
+	}
+
+	c.config.Logger.Printf("Processing resources using up to %d goroutines\n", concurrency)
+
+	running := 0
+	runningSerial := false
+	stopped := false
+	for {
+		// Start as many ready resources as concurrency allows
+		for running < concurrency {
+			var r resource.Resource
+			switch {
+			case !runningSerial && len(readySerial) > 0:
+				r, readySerial = readySerial[0], readySerial[1:]
+				runningSerial = true
+			case len(ready) > 0:
+				r, ready = ready[0], ready[1:]
+			}
+
+			if r == nil {
+				break
+			}
+
+			// Ready resources with the same batch
+			// key are processed together
+			batch := []resource.Resource{r}
+			if key := batchKey(r); key != "" {
+				var others []resource.Resource
+				if r.IsConcurrent() {
+					others, ready = takeBatch(key, ready)
+				} else {
+					others, readySerial = takeBatch(key, readySerial)
+				}
+				batch = append(batch, others...)
+			}
+
+			running++
+			go process(batch, stopped)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		// Wait for resources to be processed and
+		// schedule the resources depending on them
+		resources := <-done
+		running--
+		for _, r := range resources {
+			if !r.IsConcurrent() {
+				runningSerial = false
+			}
+
+			if c.config.FailurePolicy == FailurePolicyFailFast && !stopped && c.hasFailed(r.ID()) {
+				c.config.Logger.Printf("%s failed, skipping remaining resources\n", r.ID())
+				stopped = true
+			}
+
+			for _, dependent := range c.reversed.Nodes[r.ID()].Edges {
+				pending[dependent.Name]--
+				if pending[dependent.Name] == 0 {
+					enqueue(c.collection[dependent.Name])
+				}
+			}
 		}
 	}
 
-	close(ch)
-	wg.Wait()
+	if err := c.saveState(); err != nil {
+		c.config.Logger.Printf("Unable to save state: %s\n", err)
+	}
 
 	return c.status
 }
 
+// record stores the status of a processed resource
+// and emits the events for it
+func (c *Catalog) record(r resource.Resource, item *StatusItem) {
+	id := r.ID()
+	if err := c.runFailureTriggers(r, item); err != nil && item.Err == nil {
+		item.Err = err
+	}
+
+	c.status.Lock()
+	c.status.Items[id] = item
+	c.status.Unlock()
+	if item.Err != nil {
+		c.config.Logger.Printf("%s %s\n", id, item.Err)
+	}
+
+	switch {
+	case item.Skipped:
+		c.emit(&Event{Type: EventSkipped, ID: id, Item: item, Err: item.Err})
+	case item.Err != nil:
+		c.emit(&Event{Type: EventFailed, ID: id, Item: item, Err: item.Err})
+	}
+	c.emit(&Event{Type: EventProcessed, ID: id, Item: item, Err: item.Err})
+}
+
+// execution contains the state of a resource being processed
+type execution struct {
+	// Resource being processed
+	r resource.Resource
+
+	// Context used for processing the resource
+	ctx context.Context
+
+	// Cancels the context of the resource, if it has a
+	// processing timeout
+	cancel context.CancelFunc
+
+	// Status of the resource
+	item *StatusItem
+
+	// Evaluated state of the resource
+	state resource.State
+
+	// Action to be taken for the resource and the
+	// event which is emitted once it is done
+	action func(context.Context, resource.Resource) error
+	event  string
+
+	// Indicates whether the resource has been initialized
+	initialized bool
+
+	// Indicates whether processing of the resource has finished,
+	// e.g. because of an error or the resource being skipped
+	finished bool
+}
+
 // execute processes a single resource
-func (c *Catalog) execute(r resource.Resource) *StatusItem {
+func (c *Catalog) execute(ctx context.Context, r resource.Resource) *StatusItem {
+	e := c.begin(ctx, r)
+	c.act(e)
+	c.complete(e)
+	c.end(e)
+
+	return e.item
+}
+
+// begin starts processing of a resource by evaluating it and
+// determining the action, which should be taken for the resource.
+func (c *Catalog) begin(ctx context.Context, r resource.Resource) *execution {
+	e := &execution{
+		r:   r,
+		ctx: ctx,
+		item: &StatusItem{
+			ID:         r.ID(),
+			Properties: make([]PropertyChange, 0),
+			Triggers:   make([]string, 0),
+			Start:      time.Now(),
+		},
+	}
+	item := e.item
+
+	fail := func(err error, skipped bool) *execution {
+		item.Err = err
+		item.Skipped = skipped
+		e.finished = true
+		return e
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(err, true)
+	}
+
 	if err := c.hasFailedDependencies(r); err != nil {
-		return &StatusItem{Err: err}
+		return fail(err, true)
 	}
 
 	if err := r.Validate(); err != nil {
-		return &StatusItem{Err: err}
+		return fail(err, false)
 	}
 
-	if err := r.Initialize(); err != nil {
-		return &StatusItem{Err: err}
+	if timeout := r.ProcessingTimeout(); timeout > 0 {
+		e.ctx, e.cancel = context.WithTimeout(ctx, timeout)
 	}
-	defer r.Close()
 
-	state, err := r.Evaluate()
+	reason, err := c.checkGuards(e.ctx, r)
 	if err != nil {
-		return &StatusItem{Err: err}
+		return fail(err, false)
+	}
+	if reason != "" {
+		c.config.Logger.Printf("%s skipped, %s\n", r.ID(), reason)
+		return fail(nil, true)
 	}
 
-	if c.config.DryRun {
-		return &StatusItem{}
+	if err := initialize(e.ctx, r); err != nil {
+		return fail(err, false)
 	}
+	e.initialized = true
+
+	err = c.retry(e.ctx, r, item, func() error {
+		var err error
+		e.state, err = evaluate(e.ctx, r)
+		return err
+	})
+	item.StateBefore = e.state.Current
+	item.StateAfter = e.state.Current
+	if err != nil {
+		return fail(err, false)
+	}
+	c.emit(&Event{Type: EventEvaluated, ID: r.ID(), State: &e.state})
 
 	// Current and wanted states for the resource
-	want := utils.NewString(state.Want)
-	current := utils.NewString(state.Current)
+	want := utils.NewString(e.state.Want)
+	current := utils.NewString(e.state.Current)
 
 	// The list of present and absent states for the resource
 	present := utils.NewList(r.PresentStates()...)
 	absent := utils.NewList(r.AbsentStates()...)
 
-	// Process resource
+	// Determine the action for the resource
 	id := r.ID()
-	var action func() error
 	switch {
 	case want.IsInList(present) && current.IsInList(absent):
-		action = r.Create
+		e.action = create
+		e.event = EventCreated
+		item.Transition = TransitionCreate
 		c.config.Logger.Printf("%s is %s, should be %s\n", id, current, want)
 	case want.IsInList(absent) && current.IsInList(present):
-		action = r.Delete
+		e.action = remove
+		e.event = EventDeleted
+		item.Transition = TransitionDelete
 		c.config.Logger.Printf("%s is %s, should be %s\n", id, current, want)
 	default:
 		// No-op: resource is in sync
 	}
 
-	stateChanged := false
-	if action != nil {
-		stateChanged = true
-		if err := action(); err != nil {
-			return &StatusItem{StateChanged: true, Err: err}
-		}
+	if e.action != nil {
+		item.StateChanged = true
+	}
+
+	return e
+}
+
+// act takes the action determined for the resource, if any
+func (c *Catalog) act(e *execution) {
+	if e.finished || e.action == nil {
+		return
 	}
 
-	// Process resource properties
-	for _, p := range r.Properties() {
-		synced, err := p.IsSynced()
+	id := e.r.ID()
+	if c.config.DryRun {
+		c.config.Logger.Printf("%s would %s resource\n", id, e.item.Transition)
+	} else if err := c.retry(e.ctx, e.r, e.item, func() error { return e.action(e.ctx, e.r) }); err != nil {
+		e.item.Err = err
+		e.finished = true
+		return
+	}
+
+	c.acted(e)
+}
+
+// acted records that the action for the resource has been taken
+func (c *Catalog) acted(e *execution) {
+	e.item.StateAfter = e.state.Want
+	e.action = nil
+	c.emit(&Event{Type: e.event, ID: e.r.ID()})
+}
+
+// complete processes the properties of the resource, refreshes it if
+// needed and runs the triggers of resources subscribed to it.
+func (c *Catalog) complete(e *execution) {
+	if e.finished {
+		return
+	}
+
+	id, item := e.r.ID(), e.item
+	for _, p := range e.r.Properties() {
+		// Purged resources are rebuilt with the default values
+		// of their properties, which are not the wanted ones
+		if c.purged[id] {
+			break
+		}
+
+		synced, err := isSynced(e.ctx, p)
 		if err != nil {
 			// Some properties make no sense if the resource is absent, e.g.
 			// setting up file permissions requires that the file managed by the
@@ -315,58 +879,240 @@ func (c *Catalog) execute(r resource.Resource) *StatusItem {
 			if err == resource.ErrResourceAbsent {
 				continue
 			}
-			e := fmt.Errorf("unable to evaluate property %s: %s\n", p.Name, err)
-			return &StatusItem{StateChanged: true, Err: e}
+			item.Err = fmt.Errorf("unable to evaluate property %s: %s\n", p.Name(), err)
+			return
 		}
 
 		if !synced {
-			stateChanged = true
+			item.StateChanged = true
+			item.Properties = append(item.Properties, propertyChange(e.ctx, p))
 			c.config.Logger.Printf("%s property '%s' is out of date\n", id, p.Name())
-			if err := p.Set(); err != nil {
-				e := fmt.Errorf("unable to set property %s: %s\n", p.Name, err)
-				return &StatusItem{StateChanged: true, Err: e}
+			if !c.config.DryRun {
+				if err := c.retry(e.ctx, e.r, item, func() error { return set(e.ctx, p) }); err != nil {
+					item.Err = fmt.Errorf("unable to set property %s: %s\n", p.Name(), err)
+					return
+				}
 			}
+			c.emit(&Event{Type: EventPropertySynced, ID: id, Property: p.Name()})
 		}
 	}
 
-	if err := c.runTriggers(r); err != nil {
-		return &StatusItem{StateChanged: stateChanged, Err: err}
+	if err := c.refresh(e.ctx, e.r, item); err != nil {
+		item.Err = err
+		return
 	}
 
-	return &StatusItem{StateChanged: stateChanged, Err: nil}
+	if err := c.runTriggers(e.r, item); err != nil {
+		item.Err = err
+		return
+	}
 }
 
-// runTriggers executes the triggers for each
-// monitored resource if it's state has changed
-func (c *Catalog) runTriggers(r resource.Resource) error {
-	c.status.Lock()
-	defer c.status.Unlock()
+// end finishes processing of the resource
+func (c *Catalog) end(e *execution) {
+	if e.initialized {
+		e.r.Close()
+	}
+
+	if e.cancel != nil {
+		e.cancel()
+	}
 
-	for subscribed, trigger := range r.SubscribedTo() {
-		item := c.status.Items[subscribed]
-		if !item.StateChanged {
+	e.finished = true
+	e.item.finish()
+}
+
+// refresh refreshes the resource once, if any of the
+// resources notifying it have changed. Resources which have just
+// been created or deleted are not refreshed.
+func (c *Catalog) refresh(ctx context.Context, r resource.Resource, item *StatusItem) error {
+	if item.Transition != "" || !c.isNotified(r.ID()) {
+		return nil
+	}
+
+	id := r.ID()
+	item.Refreshed = true
+	item.StateChanged = true
+	if c.config.DryRun {
+		c.config.Logger.Printf("%s would refresh resource\n", id)
+		c.emit(&Event{Type: EventRefreshed, ID: id})
+		return nil
+	}
+
+	c.config.Logger.Printf("%s refreshing resource\n", id)
+	refresher := r.(resource.Refresher)
+
+	err := c.retry(ctx, r, item, func() error { return refresh(ctx, refresher) })
+	if err != nil {
+		return err
+	}
+	c.emit(&Event{Type: EventRefreshed, ID: id})
+
+	return nil
+}
+
+// isNotified returns true if any of the resources
+// notifying the given resource id have changed.
+func (c *Catalog) isNotified(id string) bool {
+	c.status.RLock()
+	defer c.status.RUnlock()
+
+	for _, notifier := range c.notifiedBy[id] {
+		item, ok := c.status.Items[notifier]
+		if ok && item.StateChanged && item.Err == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// propertyChange creates a new PropertyChange for an out of date property.
+func propertyChange(ctx context.Context, p resource.Property) PropertyChange {
+	change := PropertyChange{
+		Name: p.Name(),
+	}
+
+	if v, ok := p.(resource.PropertyValuer); ok {
+		// Values are informational only, so errors are ignored here
+		current, want, err := values(ctx, v)
+		if err == nil {
+			change.Old = current
+			change.New = want
+		}
+	}
+
+	return change
+}
+
+// triggerKind type describes a kind of triggers and
+// the condition for executing them.
+type triggerKind struct {
+	// Triggers of the resource
+	triggers resource.TriggerMap
+
+	// Reason logged when executing the triggers
+	reason string
+
+	// Reason logged in dry-run mode
+	dryRunReason string
+
+	// Match reports whether the triggers should be executed
+	// based on the status of the monitored resource
+	match func(item *StatusItem) bool
+}
+
+// runTriggers executes the triggers of a processed resource for
+// each monitored resource, which has changed, succeeded or was
+// unchanged. Triggers are called with a table describing the
+// change of the monitored resource.
+func (c *Catalog) runTriggers(r resource.Resource, item *StatusItem) error {
+	kinds := []triggerKind{
+		{
+			triggers:     r.SubscribedTo(),
+			reason:       "has changed",
+			dryRunReason: "would change",
+			match:        func(i *StatusItem) bool { return i.StateChanged },
+		},
+		{
+			triggers:     r.SuccessTriggers(),
+			reason:       "has succeeded",
+			dryRunReason: "would succeed",
+			match:        func(i *StatusItem) bool { return !i.Skipped && i.Err == nil },
+		},
+		{
+			triggers:     r.UnchangedTriggers(),
+			reason:       "is unchanged",
+			dryRunReason: "would be unchanged",
+			match:        func(i *StatusItem) bool { return !i.Skipped && !i.StateChanged && i.Err == nil },
+		},
+	}
+
+	for _, kind := range kinds {
+		if err := c.fireTriggers(r, item, kind); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runFailureTriggers executes the triggers of a resource for each
+// monitored resource, which has failed. Failure triggers are
+// executed even if the resource itself has failed or was skipped.
+func (c *Catalog) runFailureTriggers(r resource.Resource, item *StatusItem) error {
+	kind := triggerKind{
+		triggers:     r.FailureTriggers(),
+		reason:       "has failed",
+		dryRunReason: "has failed",
+		match:        func(i *StatusItem) bool { return !i.Skipped && i.Err != nil },
+	}
+
+	return c.fireTriggers(r, item, kind)
+}
+
+// fireTriggers executes the triggers of the given kind, for which the
+// status of the monitored resource matches the trigger condition.
+func (c *Catalog) fireTriggers(r resource.Resource, item *StatusItem, kind triggerKind) error {
+	for subscribed, trigger := range kind.triggers {
+		monitored := c.matchingItem(subscribed, kind.match)
+		if monitored == nil {
 			continue
 		}
 
-		c.config.Logger.Printf("%s running trigger, because %s has changed\n", r.ID(), subscribed)
-		c.config.L.Push(trigger)
-		if err := c.config.L.PCall(0, 0, nil); err != nil {
+		item.Triggers = append(item.Triggers, subscribed)
+		if c.config.DryRun {
+			c.config.Logger.Printf("%s would run trigger, because %s %s\n", r.ID(), subscribed, kind.dryRunReason)
+			c.emit(&Event{Type: EventTriggerFired, ID: r.ID(), Subscribed: subscribed})
+			continue
+		}
+
+		c.config.Logger.Printf("%s running trigger, because %s %s\n", r.ID(), subscribed, kind.reason)
+		trigger := trigger
+		err := c.lua.Do(func(L *lua.LState) error {
+			L.Push(trigger)
+			L.Push(changeTable(L, monitored))
+			return L.PCall(1, 0, nil)
+		})
+		if err != nil {
 			c.config.Logger.Printf("%s trigger exited with an error: %s\n", r.ID(), err)
 			return err
 		}
+		c.emit(&Event{Type: EventTriggerFired, ID: r.ID(), Subscribed: subscribed})
 	}
 
 	return nil
 }
 
+// matchingItem returns the status item of a processed
+// resource, if the status matches the given condition.
+func (c *Catalog) matchingItem(id string, match func(item *StatusItem) bool) *StatusItem {
+	c.status.RLock()
+	defer c.status.RUnlock()
+
+	item, ok := c.status.Items[id]
+	if !ok || !match(item) {
+		return nil
+	}
+
+	return item
+}
+
 // hasFailedDependencies checks if a resource has failed dependencies.
 func (c *Catalog) hasFailedDependencies(r resource.Resource) error {
 	c.status.Lock()
 	defer c.status.Unlock()
 
-	for _, dep := range r.Dependencies() {
-		item := c.status.Items[dep]
-		if item.Err != nil {
+	for _, dep := range c.dependencies[r.ID()] {
+		item, ok := c.status.Items[dep]
+		if !ok || item.Err == nil {
+			continue
+		}
+
+		// Dependencies which have been skipped because of
+		// failures further up the graph are considered as
+		// failed only when failing whole subtrees
+		if !item.Skipped || c.config.FailurePolicy == FailurePolicyFailSubtree {
 			return fmt.Errorf("failed dependency for %s", dep)
 		}
 	}
@@ -374,6 +1120,16 @@ func (c *Catalog) hasFailedDependencies(r resource.Resource) error {
 	return nil
 }
 
+// hasFailed checks if processing of a resource has failed.
+func (c *Catalog) hasFailed(id string) bool {
+	c.status.RLock()
+	defer c.status.RUnlock()
+
+	item, ok := c.status.Items[id]
+
+	return ok && item.Err != nil && !item.Skipped
+}
+
 // luaLen returns the number of unsorted resources in catalog.
 // This method is called from Lua.
 func (c *Catalog) luaLen() int {
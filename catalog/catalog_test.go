@@ -26,10 +26,21 @@
 package catalog
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/coreos/go-systemd/util"
 	"github.com/dnaeon/gru/resource"
 	"github.com/yuin/gopher-lua"
 )
@@ -77,3 +88,1335 @@ func TestCatalog(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestStatusReport(t *testing.T) {
+	status := &Status{
+		Items: map[string]*StatusItem{
+			"file[foo]": &StatusItem{ID: "file[foo]", StateChanged: true},
+			"file[bar]": &StatusItem{ID: "file[bar]"},
+			"file[qux]": &StatusItem{ID: "file[qux]", Err: errors.New("qux failed")},
+		},
+	}
+
+	report := status.Report()
+	if report.Changed != 1 || report.UpToDate != 1 || report.Failed != 1 {
+		t.Errorf("want 1 changed, 1 up-to-date and 1 failed, got %d, %d and %d\n", report.Changed, report.UpToDate, report.Failed)
+	}
+
+	data, err := json.Marshal(status.Items["file[qux]"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var item map[string]interface{}
+	if err := json.Unmarshal(data, &item); err != nil {
+		t.Fatal(err)
+	}
+
+	if item["error"] != "qux failed" {
+		t.Errorf("want error 'qux failed', got %v\n", item["error"])
+	}
+}
+
+// loadModule writes the Lua code to the module of the configuration,
+// or to a temporary module if none is set, and loads a catalog from it.
+// The configuration must provide the Lua state. Unless another logger
+// is set, the catalog logs to a discarding logger. The given resources
+// are added to the catalog in addition to the ones from the module.
+func loadModule(t *testing.T, code string, config *Config, resources ...resource.Resource) (*Catalog, error) {
+	c := *config
+	if c.Module == "" {
+		f, err := ioutil.TempFile("", "gru-module")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+		c.Module = f.Name()
+	}
+
+	if err := ioutil.WriteFile(c.Module, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.New(ioutil.Discard, "", log.LstdFlags)
+	}
+
+	katalog := New(&c)
+	katalog.Add(resources...)
+
+	return katalog, katalog.Load()
+}
+
+// runModule loads a catalog from the Lua code using the given
+// configuration and processes it. If the configuration does not
+// provide a Lua state, a new one is used for the run.
+func runModule(t *testing.T, code string, config *Config) *Status {
+	c := *config
+	if c.L == nil {
+		L := lua.NewState()
+		defer L.Close()
+		c.L = L
+	}
+
+	katalog, err := loadModule(t, code, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return katalog.Run()
+}
+
+func TestCatalogDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo")
+	code := fmt.Sprintf(`
+	foo = resource.file.new(%q)
+	foo.state = "present"
+	catalog:add(foo)
+	`, path)
+
+	status := runModule(t, code, &Config{DryRun: true})
+	item := status.Items[fmt.Sprintf("file[%s]", path)]
+	if item == nil {
+		t.Fatal("missing status for file resource")
+	}
+
+	if !item.StateChanged || item.Transition != TransitionCreate {
+		t.Errorf("want a pending create transition, got %q\n", item.Transition)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file %s should not exist after a dry run\n", path)
+	}
+}
+
+func TestCatalogRunOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each chain creates a directory and a file within it, which
+	// can only succeed if the directory is processed first
+	code := fmt.Sprintf(`
+	for i = 1, 10 do
+	   local path = %q .. "/dir" .. i
+	   local d = resource.directory.new(path)
+	   local f = resource.file.new(path .. "/file")
+	   f.require = { d:ID() }
+	   catalog:add(d, f)
+	end
+	`, dir)
+
+	report := runModule(t, code, &Config{Concurrency: 4}).Report()
+	if report.Changed != 20 || report.Failed != 0 {
+		t.Errorf("want 20 changed and 0 failed resources, got %d and %d\n", report.Changed, report.Failed)
+	}
+}
+
+func TestCatalogResourceTimeout(t *testing.T) {
+	code := `
+	slow = resource.shell.new("sleep 5")
+	slow.timeout = 1
+	after = resource.shell.new("true")
+	after.require = { slow:ID() }
+	catalog:add(slow, after)
+	`
+
+	status := runModule(t, code, &Config{})
+	slow := status.Items["shell[sleep 5]"]
+	if slow.Err == nil || slow.Skipped {
+		t.Errorf("want shell[sleep 5] to fail, got %v\n", slow.Err)
+	}
+
+	after := status.Items["shell[true]"]
+	if !after.Skipped {
+		t.Errorf("want shell[true] to be skipped\n")
+	}
+}
+
+// slowResource is a resource, which does not support cancellation
+// and records the order of the operations performed on it
+type slowResource struct {
+	resource.Base
+	sync.Mutex
+	operations []string
+}
+
+func newSlowResource(name string) *slowResource {
+	r := &slowResource{
+		Base: resource.Base{
+			Name:              name,
+			Type:              "slow",
+			State:             "present",
+			Require:           make([]string, 0),
+			PresentStatesList: []string{"present"},
+			AbsentStatesList:  []string{"absent"},
+			Concurrent:        true,
+			Subscribe:         make(resource.TriggerMap),
+			OnFailure:         make(resource.TriggerMap),
+			OnSuccess:         make(resource.TriggerMap),
+			OnUnchanged:       make(resource.TriggerMap),
+		},
+	}
+
+	return r
+}
+
+func (r *slowResource) record(operation string) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.operations = append(r.operations, operation)
+}
+
+func (r *slowResource) Evaluate() (resource.State, error) {
+	return resource.State{Current: "absent", Want: r.State}, nil
+}
+
+func (r *slowResource) Create() error {
+	time.Sleep(200 * time.Millisecond)
+	r.record("create")
+
+	return nil
+}
+
+func (r *slowResource) Delete() error {
+	return nil
+}
+
+func (r *slowResource) Close() error {
+	r.record("close")
+
+	return nil
+}
+
+func TestCatalogTimeoutWaits(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	// The deadline expires while creating the slow resource, which
+	// must not be abandoned or closed before it has been created
+	slow := newSlowResource("a")
+	after := newSlowResource("b")
+	after.Require = []string{slow.ID()}
+	katalog, err := loadModule(t, "", &Config{L: L, Timeout: 50 * time.Millisecond}, slow, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := katalog.Run()
+	slow.Lock()
+	operations := slow.operations
+	slow.Unlock()
+
+	want := []string{"create", "close"}
+	if !reflect.DeepEqual(want, operations) {
+		t.Errorf("want %q operations, got %q\n", want, operations)
+	}
+
+	if item := status.Items[after.ID()]; !item.Skipped || item.Err != context.DeadlineExceeded {
+		t.Errorf("want %s to be skipped after the deadline, got %v\n", after.ID(), item.Err)
+	}
+}
+
+func TestCatalogRetries(t *testing.T) {
+	code := `
+	sh = resource.shell.new("false")
+	sh.retries = 2
+	catalog:add(sh)
+	`
+
+	item := runModule(t, code, &Config{}).Items["shell[false]"]
+	if item.Err == nil {
+		t.Errorf("want shell[false] to fail\n")
+	}
+
+	if item.Retries != 2 {
+		t.Errorf("want 2 retries, got %d\n", item.Retries)
+	}
+}
+
+func TestCatalogNotify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	marker := filepath.Join(dir, "refreshed")
+	code := fmt.Sprintf(`
+	sh = resource.shell.new("touch %s")
+	sh.creates = "%s"
+	sh.mute = true
+
+	f = resource.file.new("%s")
+	f.state = "present"
+	f.notify = { sh:ID() }
+
+	catalog:add(sh, f)
+	`, marker, dir, filepath.Join(dir, "foo"))
+
+	status := runModule(t, code, &Config{})
+	item := status.Items[fmt.Sprintf("shell[touch %s]", marker)]
+	if item.Err != nil {
+		t.Fatal(item.Err)
+	}
+
+	if !item.Refreshed {
+		t.Errorf("want notified resource to be refreshed\n")
+	}
+
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("want refresh to execute the command: %s\n", err)
+	}
+}
+
+func TestCatalogSelection(t *testing.T) {
+	code := `
+	pkg = resource.shell.new("true")
+	pkg.tags = { "packages" }
+
+	config = resource.shell.new("echo config")
+	config.mute = true
+	config.tags = { "config" }
+	config.require = { pkg:ID() }
+
+	svc = resource.shell.new("echo service")
+	svc.mute = true
+	svc.require = { config:ID() }
+
+	catalog:add(pkg, config, svc)
+	`
+
+	testCases := []struct {
+		tags     []string
+		skipTags []string
+		only     []string
+		want     []string
+	}{
+		{
+			want: []string{"shell[true]", "shell[echo config]", "shell[echo service]"},
+		},
+		{
+			tags: []string{"config"},
+			want: []string{"shell[true]", "shell[echo config]"},
+		},
+		{
+			tags:     []string{"config"},
+			skipTags: []string{"packages"},
+			want:     []string{"shell[echo config]"},
+		},
+		{
+			skipTags: []string{"packages"},
+			want:     []string{"shell[echo config]", "shell[echo service]"},
+		},
+		{
+			only:     []string{"shell[echo service]"},
+			skipTags: []string{"config"},
+			want:     []string{"shell[true]", "shell[echo service]"},
+		},
+	}
+
+	for _, tc := range testCases {
+		config := &Config{
+			Tags:     tc.tags,
+			SkipTags: tc.skipTags,
+			Only:     tc.only,
+		}
+
+		status := runModule(t, code, config)
+		if len(status.Items) != len(tc.want) {
+			t.Errorf("want %d processed resources, got %d\n", len(tc.want), len(status.Items))
+		}
+
+		for _, id := range tc.want {
+			item, ok := status.Items[id]
+			if !ok {
+				t.Errorf("want %s to be processed\n", id)
+				continue
+			}
+			if item.Err != nil {
+				t.Errorf("%s failed: %s\n", id, item.Err)
+			}
+		}
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	config := &Config{
+		L:    L,
+		Only: []string{"shell[unknown]"},
+	}
+
+	if _, err := loadModule(t, code, config); err == nil {
+		t.Errorf("want error when selecting a resource which does not exist\n")
+	}
+}
+
+func TestCatalogGuards(t *testing.T) {
+	code := `
+	a = resource.shell.new("echo a")
+	a.mute = true
+	a.only_if = "true"
+
+	b = resource.shell.new("echo b")
+	b.mute = true
+	b.only_if = "false"
+
+	c = resource.shell.new("echo c")
+	c.mute = true
+	c.unless = function() return true end
+
+	d = resource.shell.new("echo d")
+	d.mute = true
+	d.unless = function() return false end
+	d.require = { b:ID(), c:ID() }
+
+	catalog:add(a, b, c, d)
+	`
+
+	status := runModule(t, code, &Config{Concurrency: 2})
+	want := map[string]bool{
+		"shell[echo a]": false,
+		"shell[echo b]": true,
+		"shell[echo c]": true,
+		"shell[echo d]": false,
+	}
+
+	for id, skipped := range want {
+		item := status.Items[id]
+		if item.Err != nil {
+			t.Errorf("%s failed: %s\n", id, item.Err)
+		}
+		if item.Skipped != skipped {
+			t.Errorf("want %s skipped %t, got %t\n", id, skipped, item.Skipped)
+		}
+	}
+
+	report := status.Report()
+	if report.Failed != 0 || report.Skipped != 2 {
+		t.Errorf("want 0 failed and 2 skipped resources, got %d failed and %d skipped\n", report.Failed, report.Skipped)
+	}
+}
+
+func TestCatalogFailurePolicy(t *testing.T) {
+	code := `
+	a = resource.shell.new("false")
+
+	b = resource.shell.new("echo b")
+	b.mute = true
+	b.require = { a:ID() }
+
+	c = resource.shell.new("echo c")
+	c.mute = true
+	c.require = { b:ID() }
+
+	catalog:add(a, b, c)
+	`
+
+	testCases := []struct {
+		policy string
+		want   map[string]error
+	}{
+		{
+			policy: FailurePolicyContinue,
+			want: map[string]error{
+				"shell[echo b]": errors.New("failed dependency for shell[false]"),
+				"shell[echo c]": nil,
+			},
+		},
+		{
+			policy: FailurePolicyFailSubtree,
+			want: map[string]error{
+				"shell[echo b]": errors.New("failed dependency for shell[false]"),
+				"shell[echo c]": errors.New("failed dependency for shell[echo b]"),
+			},
+		},
+		{
+			policy: FailurePolicyFailFast,
+			want: map[string]error{
+				"shell[echo b]": errStopped,
+				"shell[echo c]": errStopped,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		status := runModule(t, code, &Config{FailurePolicy: tc.policy})
+		for id, want := range tc.want {
+			item := status.Items[id]
+			if fmt.Sprint(want) != fmt.Sprint(item.Err) {
+				t.Errorf("%s: want %s error %v, got %v\n", tc.policy, id, want, item.Err)
+			}
+			if item.Skipped != (want != nil) {
+				t.Errorf("%s: want %s skipped %t, got %t\n", tc.policy, id, want != nil, item.Skipped)
+			}
+		}
+	}
+}
+
+func TestCatalogBefore(t *testing.T) {
+	code := `
+	pkg = resource.shell.new("echo package")
+	pkg.mute = true
+
+	repo = resource.shell.new("false")
+	repo.before = { pkg:ID() }
+
+	key = resource.shell.new("echo key")
+	key.mute = true
+	key.required_by = { repo:ID() }
+
+	catalog:add(pkg, repo, key)
+	`
+
+	status := runModule(t, code, &Config{Concurrency: 4})
+	key := status.Items["shell[echo key]"]
+	repo := status.Items["shell[false]"]
+	pkg := status.Items["shell[echo package]"]
+
+	if repo.Start.Before(key.End) {
+		t.Errorf("want shell[echo key] to be processed before shell[false]\n")
+	}
+
+	if !pkg.Skipped || pkg.Err == nil {
+		t.Errorf("want shell[echo package] to be skipped because of failed dependency\n")
+	}
+}
+
+func TestCatalogObserver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	code := fmt.Sprintf(`
+	f = resource.file.new("%s")
+	f.state = "present"
+
+	sh = resource.shell.new("false")
+	sh.subscribe[f:ID()] = function() end
+
+	catalog:add(f, sh)
+	`, filepath.Join(dir, "foo"))
+
+	events := make(map[string][]string)
+	observer := ObserverFunc(func(e *Event) {
+		events[e.ID] = append(events[e.ID], e.Type)
+	})
+
+	runModule(t, code, &Config{Concurrency: 2, Observers: []Observer{observer}})
+
+	want := map[string][]string{
+		fmt.Sprintf("file[%s]", filepath.Join(dir, "foo")): {
+			EventLoaded,
+			EventScheduled,
+			EventEvaluated,
+			EventCreated,
+			EventProcessed,
+		},
+		"shell[false]": {
+			EventLoaded,
+			EventScheduled,
+			EventEvaluated,
+			EventFailed,
+			EventProcessed,
+		},
+	}
+
+	if !reflect.DeepEqual(want, events) {
+		t.Errorf("want %q events, got %q\n", want, events)
+	}
+}
+
+func TestCatalogCompile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The content of source files is embedded in the compiled catalog
+	siteRepo := filepath.Join(dir, "site")
+	if err := os.Mkdir(siteRepo, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(siteRepo, "foo.txt"), []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "foo")
+	code := fmt.Sprintf(`
+	f = resource.file.new("%s")
+	f.state = "present"
+	f.mode = tonumber("0600", 8)
+	f.source = "foo.txt"
+	f.tags = { "config" }
+
+	sh = resource.shell.new("echo bar")
+	sh.mute = true
+	sh.unless = "false"
+	sh.require = { f:ID() }
+
+	catalog:add(f, sh)
+	`, path)
+
+	L := lua.NewState()
+	defer L.Close()
+
+	katalog, err := loadModule(t, code, &Config{L: L, SiteRepo: siteRepo})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compiled, err := katalog.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(compiled)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.RemoveAll(siteRepo); err != nil {
+		t.Fatal(err)
+	}
+
+	catalogFile := filepath.Join(dir, "catalog.json")
+	if err := ioutil.WriteFile(catalogFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{
+		Catalog:     catalogFile,
+		Logger:      log.New(ioutil.Discard, "", log.LstdFlags),
+		L:           L,
+		Concurrency: 1,
+	}
+
+	katalog = New(config)
+	if err := katalog.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	status := katalog.Run()
+	for id, item := range status.Items {
+		if item.Err != nil {
+			t.Errorf("%s failed: %s\n", id, item.Err)
+		}
+		if item.Skipped {
+			t.Errorf("want %s to be processed\n", id)
+		}
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fi.Mode() != 0600 {
+		t.Errorf("want file mode 0600, got %s\n", fi.Mode())
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != "foo" {
+		t.Errorf("want file content 'foo', got '%s'\n", content)
+	}
+}
+
+func TestCatalogCompileTriggers(t *testing.T) {
+	code := `
+	pkg = resource.shell.new("true")
+
+	svc = resource.shell.new("echo restart")
+	svc.subscribe[pkg:ID()] = function() end
+
+	catalog:add(pkg, svc)
+	`
+
+	L := lua.NewState()
+	defer L.Close()
+
+	katalog, err := loadModule(t, code, &Config{L: L})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := katalog.Compile(); err == nil {
+		t.Errorf("want error when compiling a catalog with Lua triggers\n")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := &Compiled{
+		Resources: []*resource.Spec{
+			{Type: "file", Name: "/tmp/foo", Fields: map[string]json.RawMessage{"mode": json.RawMessage("420")}},
+			{Type: "shell", Name: "echo foo", Fields: map[string]json.RawMessage{}},
+			{Type: "shell", Name: "echo bar", Fields: map[string]json.RawMessage{}},
+		},
+	}
+
+	to := &Compiled{
+		Resources: []*resource.Spec{
+			{Type: "file", Name: "/tmp/foo", Fields: map[string]json.RawMessage{"mode": json.RawMessage("384"), "owner": json.RawMessage(`"root"`)}},
+			{Type: "shell", Name: "echo bar", Fields: map[string]json.RawMessage{}},
+			{Type: "shell", Name: "echo qux", Fields: map[string]json.RawMessage{}},
+		},
+	}
+
+	want := []ResourceDiff{
+		{
+			ID:     "file[/tmp/foo]",
+			Change: ChangeModified,
+			Fields: []FieldDiff{
+				{Name: "mode", Old: json.RawMessage("420"), New: json.RawMessage("384")},
+				{Name: "owner", New: json.RawMessage(`"root"`)},
+			},
+		},
+		{ID: "shell[echo foo]", Change: ChangeRemoved},
+		{ID: "shell[echo qux]", Change: ChangeAdded},
+	}
+
+	got := Diff(from, to)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v\n", want, got)
+	}
+}
+
+func TestCatalogPurge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	foo := filepath.Join(dir, "foo")
+	bar := filepath.Join(dir, "bar")
+	config := &Config{
+		Module:    filepath.Join(dir, "module.lua"),
+		StateFile: filepath.Join(dir, "state.json"),
+	}
+
+	runModule(t, fmt.Sprintf(`
+	catalog.purge = true
+
+	foo = resource.file.new("%s")
+	foo.state = "present"
+
+	bar = resource.file.new("%s")
+	bar.state = "present"
+	bar.mode = tonumber("0600", 8)
+
+	catalog:add(foo, bar)
+	`, foo, bar), config)
+
+	if _, err := os.Stat(bar); err != nil {
+		t.Fatal(err)
+	}
+
+	code := fmt.Sprintf(`
+	catalog.purge = true
+
+	foo = resource.file.new("%s")
+	foo.state = "present"
+
+	catalog:add(foo)
+	`, foo)
+
+	status := runModule(t, code, config)
+	item, ok := status.Items[fmt.Sprintf("file[%s]", bar)]
+	if !ok {
+		t.Fatalf("want file[%s] to be purged\n", bar)
+	}
+
+	if item.Err != nil || item.Transition != TransitionDelete {
+		t.Errorf("want file[%s] to be deleted, got transition '%s' and error %v\n", bar, item.Transition, item.Err)
+	}
+
+	if _, err := os.Stat(bar); !os.IsNotExist(err) {
+		t.Errorf("want %s to be removed\n", bar)
+	}
+
+	if _, err := os.Stat(foo); err != nil {
+		t.Errorf("want %s to be present: %s\n", foo, err)
+	}
+
+	// Purged resources are no longer part of the state
+	status = runModule(t, code, config)
+	if len(status.Items) != 1 {
+		t.Errorf("want 1 processed resource, got %d\n", len(status.Items))
+	}
+}
+
+func TestCatalogPurgeRebuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	target := filepath.Join(dir, "target")
+	if err := ioutil.WriteFile(target, []byte("target"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(dir, "link")
+	config := &Config{
+		Module:    filepath.Join(dir, "module.lua"),
+		StateFile: filepath.Join(dir, "state.json"),
+	}
+
+	runModule(t, fmt.Sprintf(`
+	catalog.purge = true
+
+	l = resource.link.new("%s")
+	l.state = "present"
+	l.source = "%s"
+
+	catalog:add(l)
+	`, link, target), config)
+
+	// Purged resources are rebuilt only from their type and name,
+	// so that removing the link target does not break purging
+	if err := os.Remove(target); err != nil {
+		t.Fatal(err)
+	}
+
+	status := runModule(t, "catalog.purge = true", config)
+	item, ok := status.Items[fmt.Sprintf("link[%s]", link)]
+	if !ok {
+		t.Fatalf("want link[%s] to be purged\n", link)
+	}
+
+	if item.Err != nil || item.Transition != TransitionDelete {
+		t.Errorf("want link[%s] to be deleted, got transition '%s' and error %v\n", link, item.Transition, item.Err)
+	}
+
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("want %s to be removed\n", link)
+	}
+}
+
+// unitResource is a resource resembling a service, which
+// is enabled by default and records the operations on it
+type unitResource struct {
+	resource.Base
+	Enable   bool   `luar:"enable"`
+	Endpoint string `luar:"endpoint"`
+
+	// remote units cannot be managed without an endpoint
+	remote     bool
+	operations []string
+}
+
+func newUnitResource(typ, name string) *unitResource {
+	r := &unitResource{
+		Base: resource.Base{
+			Name:              name,
+			Type:              typ,
+			State:             "running",
+			Require:           make([]string, 0),
+			PresentStatesList: []string{"running"},
+			AbsentStatesList:  []string{"stopped"},
+			Concurrent:        false,
+			Subscribe:         make(resource.TriggerMap),
+			OnFailure:         make(resource.TriggerMap),
+			OnSuccess:         make(resource.TriggerMap),
+			OnUnchanged:       make(resource.TriggerMap),
+		},
+		Enable: true,
+		remote: typ == "remote_unit",
+	}
+	r.PropertyList = []resource.Property{
+		&resource.ResourceProperty{
+			PropertyName:         "enable",
+			PropertySetFunc:      func() error { r.operations = append(r.operations, "enable"); return nil },
+			PropertyIsSyncedFunc: func() (bool, error) { return false, nil },
+		},
+	}
+
+	return r
+}
+
+func (r *unitResource) Validate() error {
+	if r.remote && r.Endpoint == "" {
+		return errors.New("endpoint must be set")
+	}
+
+	return r.Base.Validate()
+}
+
+func (r *unitResource) Evaluate() (resource.State, error) {
+	return resource.State{Current: "running", Want: r.State}, nil
+}
+
+func (r *unitResource) Create() error {
+	r.operations = append(r.operations, "start")
+	return nil
+}
+
+func (r *unitResource) Delete() error {
+	r.operations = append(r.operations, "stop")
+	return nil
+}
+
+func TestCatalogPurgeProperties(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	units := make(map[string]*unitResource)
+	for _, typ := range []string{"unit", "remote_unit"} {
+		typ := typ
+		resource.RegisterProvider(resource.ProviderItem{
+			Type: typ,
+			Provider: func(name string) (resource.Resource, error) {
+				units[typ] = newUnitResource(typ, name)
+				return units[typ], nil
+			},
+			Namespace: resource.DefaultResourceNamespace,
+		})
+	}
+
+	config := &Config{
+		Module:    filepath.Join(dir, "module.lua"),
+		StateFile: filepath.Join(dir, "state.json"),
+	}
+
+	// Both units were managed during the last run, the remote
+	// unit requires an endpoint, which is not kept in the state
+	state := &localState{
+		Modules: map[string]*ModuleState{
+			config.Module: {
+				Resources: []*resource.Spec{
+					{Type: "unit", Name: "foo", Fields: map[string]json.RawMessage{"enable": json.RawMessage("false")}},
+					{Type: "remote_unit", Name: "bar", Fields: map[string]json.RawMessage{"endpoint": json.RawMessage(`"https://example.org"`)}},
+				},
+			},
+		},
+	}
+	if err := writeState(config.StateFile, state); err != nil {
+		t.Fatal(err)
+	}
+
+	status := runModule(t, "catalog.purge = true", config)
+
+	// Properties of purged resources are left alone, because
+	// the resource is rebuilt with the default values
+	item, ok := status.Items["unit[foo]"]
+	if !ok || item.Err != nil || item.Transition != TransitionDelete {
+		t.Fatalf("want unit[foo] to be purged, got %+v\n", item)
+	}
+	if want := []string{"stop"}; !reflect.DeepEqual(want, units["unit"].operations) {
+		t.Errorf("want operations %q, got %q\n", want, units["unit"].operations)
+	}
+
+	// Resources, which cannot be purged, are dropped from the state
+	if _, ok := status.Items["remote_unit[bar]"]; ok {
+		t.Errorf("want remote_unit[bar] not to be purged\n")
+	}
+
+	status = runModule(t, "catalog.purge = true", config)
+	if len(status.Items) != 0 {
+		t.Errorf("want no processed resources, got %d\n", len(status.Items))
+	}
+}
+
+func TestCatalogPurgeService(t *testing.T) {
+	if !util.IsRunningSystemd() {
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &Config{
+		Module:    filepath.Join(dir, "module.lua"),
+		StateFile: filepath.Join(dir, "state.json"),
+	}
+
+	// The unit file of a removed service is usually gone as well
+	state := &localState{
+		Modules: map[string]*ModuleState{
+			config.Module: {
+				Resources: []*resource.Spec{
+					{Type: "service", Name: "gru-missing-unit.service", Fields: map[string]json.RawMessage{"enable": json.RawMessage("true")}},
+				},
+			},
+		},
+	}
+	if err := writeState(config.StateFile, state); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		status := runModule(t, "catalog.purge = true", config)
+		if item, ok := status.Items["service[gru-missing-unit.service]"]; ok && item.Err != nil {
+			t.Fatalf("want service to be purged, got %s\n", item.Err)
+		}
+	}
+}
+
+func TestCatalogConcurrentTriggers(t *testing.T) {
+	code := `
+	counter = 0
+	for i = 1, 50 do
+	  local a = resource.shell.new("true " .. i)
+	  a.mute = true
+
+	  local b = resource.shell.new("echo " .. i)
+	  b.mute = true
+	  b.subscribe[a:ID()] = function()
+	    local t = {}
+	    for j = 1, 100 do t[j] = j end
+	    counter = counter + 1
+	  end
+
+	  catalog:add(a, b)
+	end
+	`
+
+	L := lua.NewState()
+	defer L.Close()
+
+	runModule(t, code, &Config{L: L, Concurrency: 8})
+	if counter := L.GetGlobal("counter"); counter != lua.LNumber(50) {
+		t.Errorf("want 50 triggers to be executed, got %s\n", counter)
+	}
+}
+
+func TestCatalogTriggerChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo")
+	if err := ioutil.WriteFile(path, []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code := fmt.Sprintf(`
+	f = resource.file.new("%s")
+	f.state = "present"
+	f.content = "bar"
+
+	sh = resource.shell.new("true")
+	sh.subscribe[f:ID()] = function(change)
+	  result = {
+	    id = change.id,
+	    transition = change.transition,
+	    property = change.properties[1],
+	    changed = change.old.content ~= change.new.content,
+	  }
+	end
+
+	catalog:add(f, sh)
+	`, path)
+
+	L := lua.NewState()
+	defer L.Close()
+
+	item := runModule(t, code, &Config{L: L}).Items["shell[true]"]
+	if item.Err != nil {
+		t.Fatal(item.Err)
+	}
+
+	result, ok := L.GetGlobal("result").(*lua.LTable)
+	if !ok {
+		t.Fatal("want trigger to be executed")
+	}
+
+	want := map[string]lua.LValue{
+		"id":         lua.LString(fmt.Sprintf("file[%s]", path)),
+		"transition": lua.LString(""),
+		"property":   lua.LString("content"),
+		"changed":    lua.LTrue,
+	}
+
+	for k, v := range want {
+		if got := result.RawGetString(k); got != v {
+			t.Errorf("want %s '%s', got '%s'\n", k, v, got)
+		}
+	}
+}
+
+func TestCatalogTriggerKinds(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	code := fmt.Sprintf(`
+	failed = resource.shell.new("false")
+
+	unchanged = resource.shell.new("echo unchanged")
+	unchanged.creates = "%s"
+
+	cleanup = resource.shell.new("echo cleanup")
+	cleanup.mute = true
+	cleanup.require = { failed:ID() }
+	cleanup.on_failure[failed:ID()] = function(change)
+	  failure = change.error
+	end
+
+	observer = resource.shell.new("echo observer")
+	observer.mute = true
+	observer.on_success[unchanged:ID()] = function()
+	  success = true
+	end
+	observer.on_unchanged[unchanged:ID()] = function()
+	  not_changed = true
+	end
+	observer.subscribe[unchanged:ID()] = function()
+	  changed = true
+	end
+
+	catalog:add(failed, unchanged, cleanup, observer)
+	`, dir)
+
+	L := lua.NewState()
+	defer L.Close()
+
+	item := runModule(t, code, &Config{L: L}).Items["shell[echo cleanup]"]
+	if !item.Skipped {
+		t.Errorf("want shell[echo cleanup] to be skipped\n")
+	}
+
+	if failure := L.GetGlobal("failure"); failure == lua.LNil {
+		t.Errorf("want on_failure trigger to be executed\n")
+	}
+
+	if changed := L.GetGlobal("changed"); changed != lua.LNil {
+		t.Errorf("want subscribe trigger not to be executed\n")
+	}
+
+	if success := L.GetGlobal("success"); success != lua.LTrue {
+		t.Errorf("want on_success trigger to be executed\n")
+	}
+
+	if notChanged := L.GetGlobal("not_changed"); notChanged != lua.LTrue {
+		t.Errorf("want on_unchanged trigger to be executed\n")
+	}
+}
+
+func TestCatalogCircularDependency(t *testing.T) {
+	code := `
+	a = resource.shell.new("echo a")
+	a.require = { "shell[echo b]" }
+
+	b = resource.shell.new("echo b")
+	b.require = { "shell[echo a]" }
+
+	c = resource.shell.new("echo c")
+	c.require = { a:ID() }
+
+	catalog:add(a, b, c)
+	`
+
+	L := lua.NewState()
+	defer L.Close()
+
+	_, err := loadModule(t, code, &Config{L: L})
+	want := "Circular dependency found in graph: shell[echo a] -> shell[echo b] -> shell[echo a]"
+	if err == nil || err.Error() != want {
+		t.Errorf("want error %q, got %v\n", want, err)
+	}
+}
+
+// batchRecorder records the operations on batchResource resources
+type batchRecorder struct {
+	sync.Mutex
+	loads      int
+	batches    [][]string
+	individual []string
+	installed  map[string]bool
+	fail       bool
+}
+
+// batchResource is a resource, which can be processed in batches
+type batchResource struct {
+	resource.Base
+	recorder *batchRecorder
+}
+
+func newBatchResource(name string, recorder *batchRecorder) *batchResource {
+	r := &batchResource{
+		Base: resource.Base{
+			Name:              name,
+			Type:              "batch",
+			State:             "present",
+			Require:           make([]string, 0),
+			PresentStatesList: []string{"present"},
+			AbsentStatesList:  []string{"absent"},
+			Concurrent:        false,
+			Subscribe:         make(resource.TriggerMap),
+			OnFailure:         make(resource.TriggerMap),
+			OnSuccess:         make(resource.TriggerMap),
+			OnUnchanged:       make(resource.TriggerMap),
+		},
+		recorder: recorder,
+	}
+
+	return r
+}
+
+func (r *batchResource) Evaluate() (resource.State, error) {
+	r.recorder.Lock()
+	defer r.recorder.Unlock()
+
+	s := resource.State{Current: "absent", Want: r.State}
+	if r.recorder.installed[r.Name] {
+		s.Current = "present"
+	}
+
+	return s, nil
+}
+
+func (r *batchResource) Create() error {
+	r.recorder.Lock()
+	defer r.recorder.Unlock()
+
+	r.recorder.individual = append(r.recorder.individual, r.Name)
+	r.recorder.installed[r.Name] = true
+
+	return nil
+}
+
+func (r *batchResource) Delete() error {
+	return nil
+}
+
+func (r *batchResource) BatchKey() string {
+	return "batch"
+}
+
+func (r *batchResource) NewBatch(resources []resource.Resource) resource.Batch {
+	return &recordedBatch{recorder: r.recorder}
+}
+
+// recordedBatch is the batch for batchResource resources
+type recordedBatch struct {
+	recorder *batchRecorder
+}
+
+func (b *recordedBatch) Load(ctx context.Context) error {
+	b.recorder.Lock()
+	defer b.recorder.Unlock()
+
+	b.recorder.loads++
+
+	return nil
+}
+
+func (b *recordedBatch) Create(ctx context.Context, resources []resource.Resource) error {
+	b.recorder.Lock()
+	defer b.recorder.Unlock()
+
+	if b.recorder.fail {
+		return errors.New("batch failed")
+	}
+
+	var names []string
+	for _, r := range resources {
+		name := r.(*batchResource).Name
+		names = append(names, name)
+		b.recorder.installed[name] = true
+	}
+	sort.Strings(names)
+	b.recorder.batches = append(b.recorder.batches, names)
+
+	return nil
+}
+
+func (b *recordedBatch) Delete(ctx context.Context, resources []resource.Resource) error {
+	return nil
+}
+
+func (b *recordedBatch) Close() {}
+
+func TestCatalogBatch(t *testing.T) {
+	for _, fail := range []bool{false, true} {
+		L := lua.NewState()
+		defer L.Close()
+
+		recorder := &batchRecorder{
+			installed: map[string]bool{"c": true},
+			fail:      fail,
+		}
+
+		// Resources a, b, c, e and f are ready at the same time,
+		// while d is ready only after a has been processed.
+		// Resources with a timeout or retries are not batched.
+		d := newBatchResource("d", recorder)
+		d.Require = []string{"batch[a]"}
+		e := newBatchResource("e", recorder)
+		e.Timeout = 60
+		f := newBatchResource("f", recorder)
+		f.Retries = 1
+		katalog, err := loadModule(t, "", &Config{L: L, Concurrency: 4},
+			newBatchResource("a", recorder),
+			newBatchResource("b", recorder),
+			newBatchResource("c", recorder),
+			d, e, f,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		status := katalog.Run()
+		for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+			item := status.Items["batch["+name+"]"]
+			if item.Err != nil {
+				t.Errorf("want batch[%s] to succeed, got %s", name, item.Err)
+			}
+			if changed := name != "c"; item.StateChanged != changed {
+				t.Errorf("want batch[%s] changed to be %t, got %t", name, changed, item.StateChanged)
+			}
+		}
+
+		wantBatches := [][]string{{"a", "b"}}
+		wantIndividual := []string{"d", "e", "f"}
+		wantLoads := 2
+		if fail {
+			// Resources are processed one by one if the batch fails
+			wantBatches = nil
+			wantIndividual = []string{"a", "b", "d", "e", "f"}
+			wantLoads = 1
+		}
+
+		sort.Strings(recorder.individual)
+		if !reflect.DeepEqual(wantBatches, recorder.batches) {
+			t.Errorf("want %q batches, got %q", wantBatches, recorder.batches)
+		}
+		if !reflect.DeepEqual(wantIndividual, recorder.individual) {
+			t.Errorf("want %q created individually, got %q", wantIndividual, recorder.individual)
+		}
+		if recorder.loads != wantLoads {
+			t.Errorf("want %d loads, got %d", wantLoads, recorder.loads)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package catalog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/dnaeon/gru/resource"
+)
+
+// Compiled type represents a catalog in a serialized form,
+// which can be processed without using Lua.
+type Compiled struct {
+	// Module is the name of the Lua module,
+	// from which the catalog was compiled
+	Module string `json:"module"`
+
+	// Purge specifies whether resources removed
+	// from the module should be purged
+	Purge bool `json:"purge"`
+
+	// Resources contains the serialized resources
+	// in their topological order
+	Resources []*resource.Spec `json:"resources"`
+}
+
+// Compile creates the serialized form of a loaded catalog.
+// Catalogs containing resources with Lua triggers or guards
+// cannot be compiled.
+func (c *Catalog) Compile() (*Compiled, error) {
+	return c.compile(resource.NewSpec)
+}
+
+// Describe creates the serialized form of a loaded catalog, which
+// is used for describing the catalog, e.g. when comparing catalogs.
+// Unlike Compile, catalogs containing resources with Lua triggers
+// or guards can be described, but the result may not be usable for
+// processing the catalog.
+func (c *Catalog) Describe() (*Compiled, error) {
+	return c.compile(resource.DescribeResource)
+}
+
+// compile serializes the resources from the catalog using newSpec
+func (c *Catalog) compile(newSpec func(resource.Resource) (*resource.Spec, error)) (*Compiled, error) {
+	compiled := &Compiled{
+		Module:    c.module,
+		Purge:     c.Purge,
+		Resources: make([]*resource.Spec, 0, len(c.sorted)),
+	}
+
+	for _, node := range c.sorted {
+		spec, err := newSpec(c.collection[node.Name])
+		if err != nil {
+			return nil, err
+		}
+		compiled.Resources = append(compiled.Resources, spec)
+	}
+
+	return compiled, nil
+}
+
+// loadCompiled rebuilds the resources from a compiled
+// catalog and adds them to the catalog.
+func (c *Catalog) loadCompiled(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var compiled Compiled
+	if err := json.Unmarshal(data, &compiled); err != nil {
+		return err
+	}
+
+	c.module = compiled.Module
+	c.Purge = compiled.Purge
+	for _, spec := range compiled.Resources {
+		r, err := spec.Resource()
+		if err != nil {
+			return err
+		}
+		c.Add(r)
+	}
+
+	return nil
+}
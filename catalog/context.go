@@ -0,0 +1,111 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package catalog
+
+import (
+	"context"
+
+	"github.com/dnaeon/gru/resource"
+)
+
+// The functions below dispatch to the context-aware methods of
+// resources and properties when they are implemented, and to their
+// plain counterparts otherwise. Cancellation is left to the
+// context-aware methods, so that an operation is never abandoned
+// while it is still in progress.
+
+// initialize initializes a resource using the given context
+func initialize(ctx context.Context, r resource.Resource) error {
+	if cr, ok := r.(resource.ContextInitializer); ok {
+		return cr.InitializeContext(ctx)
+	}
+
+	return r.Initialize()
+}
+
+// evaluate evaluates a resource using the given context
+func evaluate(ctx context.Context, r resource.Resource) (resource.State, error) {
+	if cr, ok := r.(resource.ContextResource); ok {
+		return cr.EvaluateContext(ctx)
+	}
+
+	return r.Evaluate()
+}
+
+// create creates a resource using the given context
+func create(ctx context.Context, r resource.Resource) error {
+	if cr, ok := r.(resource.ContextResource); ok {
+		return cr.CreateContext(ctx)
+	}
+
+	return r.Create()
+}
+
+// remove deletes a resource using the given context
+func remove(ctx context.Context, r resource.Resource) error {
+	if cr, ok := r.(resource.ContextResource); ok {
+		return cr.DeleteContext(ctx)
+	}
+
+	return r.Delete()
+}
+
+// refresh refreshes a resource using the given context
+func refresh(ctx context.Context, r resource.Refresher) error {
+	if cr, ok := r.(resource.ContextRefresher); ok {
+		return cr.RefreshContext(ctx)
+	}
+
+	return r.Refresh()
+}
+
+// set sets a property to it's desired state using the given context
+func set(ctx context.Context, p resource.Property) error {
+	if cp, ok := p.(resource.ContextProperty); ok {
+		return cp.SetContext(ctx)
+	}
+
+	return p.Set()
+}
+
+// isSynced evaluates a property using the given context
+func isSynced(ctx context.Context, p resource.Property) (bool, error) {
+	if cp, ok := p.(resource.ContextProperty); ok {
+		return cp.IsSyncedContext(ctx)
+	}
+
+	return p.IsSynced()
+}
+
+// values returns the current and desired values of
+// a property using the given context
+func values(ctx context.Context, v resource.PropertyValuer) (interface{}, interface{}, error) {
+	if cv, ok := v.(resource.ContextPropertyValuer); ok {
+		return cv.ValuesContext(ctx)
+	}
+
+	return v.Values()
+}
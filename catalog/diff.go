@@ -0,0 +1,143 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/dnaeon/gru/resource"
+)
+
+// Resource changes between catalogs
+const (
+	// ChangeAdded is used for resources, which are
+	// present only in the new catalog
+	ChangeAdded = "added"
+
+	// ChangeRemoved is used for resources, which are
+	// present only in the old catalog
+	ChangeRemoved = "removed"
+
+	// ChangeModified is used for resources, which are present
+	// in both catalogs, but have different field values
+	ChangeModified = "modified"
+)
+
+// ResourceDiff type describes how a resource differs between catalogs.
+type ResourceDiff struct {
+	// ID is the unique id of the resource
+	ID string `json:"id"`
+
+	// Change is the kind of change, e.g. added or removed
+	Change string `json:"change"`
+
+	// Fields contains the fields, which differ between catalogs.
+	// Set for modified resources only.
+	Fields []FieldDiff `json:"fields,omitempty"`
+}
+
+// FieldDiff type describes a resource field,
+// which differs between catalogs.
+type FieldDiff struct {
+	// Name of the field as seen from Lua
+	Name string `json:"name"`
+
+	// Old is the serialized value of the field in the old
+	// catalog, or nil if the field was not set
+	Old json.RawMessage `json:"old"`
+
+	// New is the serialized value of the field in the new
+	// catalog, or nil if the field is not set
+	New json.RawMessage `json:"new"`
+}
+
+// Diff compares two compiled catalogs and returns the resources,
+// which have been added, removed or modified. The result is
+// sorted by resource id.
+func Diff(from, to *Compiled) []ResourceDiff {
+	old := make(map[string]*resource.Spec)
+	for _, spec := range from.Resources {
+		old[spec.ID()] = spec
+	}
+
+	result := make([]ResourceDiff, 0)
+	for _, spec := range to.Resources {
+		id := spec.ID()
+		oldSpec, ok := old[id]
+		if !ok {
+			result = append(result, ResourceDiff{ID: id, Change: ChangeAdded})
+			continue
+		}
+		delete(old, id)
+
+		if fields := diffFields(oldSpec.Fields, spec.Fields); len(fields) > 0 {
+			result = append(result, ResourceDiff{ID: id, Change: ChangeModified, Fields: fields})
+		}
+	}
+
+	for id := range old {
+		result = append(result, ResourceDiff{ID: id, Change: ChangeRemoved})
+	}
+	sort.Sort(byResourceID(result))
+
+	return result
+}
+
+// diffFields returns the fields which differ, sorted by name.
+func diffFields(old, new map[string]json.RawMessage) []FieldDiff {
+	var result []FieldDiff
+	for name, oldValue := range old {
+		newValue, ok := new[name]
+		if !ok || !bytes.Equal(oldValue, newValue) {
+			result = append(result, FieldDiff{Name: name, Old: oldValue, New: newValue})
+		}
+	}
+
+	for name, newValue := range new {
+		if _, ok := old[name]; !ok {
+			result = append(result, FieldDiff{Name: name, New: newValue})
+		}
+	}
+	sort.Sort(byFieldName(result))
+
+	return result
+}
+
+// byResourceID sorts resource diffs by resource id
+type byResourceID []ResourceDiff
+
+func (d byResourceID) Len() int           { return len(d) }
+func (d byResourceID) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d byResourceID) Less(i, j int) bool { return d[i].ID < d[j].ID }
+
+// byFieldName sorts field diffs by field name
+type byFieldName []FieldDiff
+
+func (d byFieldName) Len() int           { return len(d) }
+func (d byFieldName) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d byFieldName) Less(i, j int) bool { return d[i].Name < d[j].Name }
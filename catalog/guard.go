@@ -0,0 +1,102 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"github.com/dnaeon/gru/resource"
+	"github.com/yuin/gopher-lua"
+)
+
+// checkGuards evaluates the only_if and unless guards of a resource.
+// It returns a non-empty reason if the resource should be skipped.
+func (c *Catalog) checkGuards(ctx context.Context, r resource.Resource) (string, error) {
+	onlyIf, unless := r.Guards()
+
+	if isGuard(onlyIf) {
+		ok, err := c.evalGuard(ctx, onlyIf)
+		if err != nil {
+			return "", fmt.Errorf("unable to evaluate only_if guard: %s", err)
+		}
+		if !ok {
+			return "only_if guard is not satisfied", nil
+		}
+	}
+
+	if isGuard(unless) {
+		ok, err := c.evalGuard(ctx, unless)
+		if err != nil {
+			return "", fmt.Errorf("unable to evaluate unless guard: %s", err)
+		}
+		if ok {
+			return "unless guard is satisfied", nil
+		}
+	}
+
+	return "", nil
+}
+
+// isGuard returns true if a guard has been set.
+func isGuard(guard lua.LValue) bool {
+	return guard != nil && guard != lua.LNil
+}
+
+// evalGuard evaluates a single guard, which is either a Lua
+// function or a shell command. The guard is satisfied if the
+// function returns a true value or the command exits with
+// a zero status.
+func (c *Catalog) evalGuard(ctx context.Context, guard lua.LValue) (bool, error) {
+	switch g := guard.(type) {
+	case *lua.LFunction:
+		var ok bool
+		err := c.lua.Do(func(L *lua.LState) error {
+			L.Push(g)
+			if err := L.PCall(0, 1, nil); err != nil {
+				return err
+			}
+			ok = lua.LVAsBool(L.Get(-1))
+			L.Pop(1)
+			return nil
+		})
+
+		return ok, err
+	case lua.LString:
+		err := exec.CommandContext(ctx, "/bin/sh", "-c", string(g)).Run()
+		if err == nil {
+			return true, nil
+		}
+		if _, ok := err.(*exec.ExitError); ok && ctx.Err() == nil {
+			return false, nil
+		}
+
+		return false, err
+	default:
+		return false, fmt.Errorf("invalid guard type %s", guard.Type())
+	}
+}
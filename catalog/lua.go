@@ -0,0 +1,124 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package catalog
+
+import (
+	"github.com/yuin/gopher-lua"
+	"layeh.com/gopher-luar"
+)
+
+// luaExecutor executes Lua code on a dedicated goroutine.
+// The Lua state is not safe for concurrent use, therefore all
+// Lua code executed while processing resources, e.g. triggers and
+// guards, goes through the executor.
+type luaExecutor struct {
+	// The Lua state owned by the executor
+	L *lua.LState
+
+	// Channel over which calls are sent to the executor
+	calls chan luaCall
+}
+
+// luaCall type represents a single call to the Lua executor
+type luaCall struct {
+	// Function to be executed with the Lua state
+	f func(L *lua.LState) error
+
+	// Channel over which the result of the call is returned
+	result chan error
+}
+
+// newLuaExecutor creates a new Lua executor and starts it
+func newLuaExecutor(L *lua.LState) *luaExecutor {
+	e := &luaExecutor{
+		L:     L,
+		calls: make(chan luaCall),
+	}
+	go e.loop()
+
+	return e
+}
+
+// loop executes the calls sent to the executor in order
+func (e *luaExecutor) loop() {
+	for call := range e.calls {
+		call.result <- call.f(e.L)
+	}
+}
+
+// Do executes f on the executor goroutine and waits for it to complete.
+func (e *luaExecutor) Do(f func(L *lua.LState) error) error {
+	result := make(chan error, 1)
+	e.calls <- luaCall{f: f, result: result}
+
+	return <-result
+}
+
+// Close stops the executor
+func (e *luaExecutor) Close() {
+	close(e.calls)
+}
+
+// changeTable creates a Lua table describing the change of a
+// processed resource, which is passed to triggers. The table
+// contains the following fields.
+//
+//	id           - id of the changed resource
+//	transition   - the transition of the resource, e.g. "create",
+//	               or an empty string if the resource was not
+//	               created or deleted
+//	state_before - state of the resource prior processing it
+//	state_after  - state of the resource after processing it
+//	refreshed    - whether the resource was refreshed
+//	properties   - list of the names of the changed properties
+//	old          - table of the old values of the changed properties
+//	new          - table of the new values of the changed properties
+//	error        - the error, if processing of the resource has failed
+func changeTable(L *lua.LState, item *StatusItem) *lua.LTable {
+	properties := L.NewTable()
+	oldValues := L.NewTable()
+	newValues := L.NewTable()
+	for _, p := range item.Properties {
+		properties.Append(lua.LString(p.Name))
+		oldValues.RawSetString(p.Name, luar.New(L, p.Old))
+		newValues.RawSetString(p.Name, luar.New(L, p.New))
+	}
+
+	change := L.NewTable()
+	change.RawSetString("id", lua.LString(item.ID))
+	change.RawSetString("transition", lua.LString(item.Transition))
+	change.RawSetString("state_before", lua.LString(item.StateBefore))
+	change.RawSetString("state_after", lua.LString(item.StateAfter))
+	change.RawSetString("refreshed", lua.LBool(item.Refreshed))
+	change.RawSetString("properties", properties)
+	change.RawSetString("old", oldValues)
+	change.RawSetString("new", newValues)
+	if item.Err != nil {
+		change.RawSetString("error", lua.LString(item.Err.Error()))
+	}
+
+	return change
+}
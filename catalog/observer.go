@@ -0,0 +1,148 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package catalog
+
+import (
+	"time"
+
+	"github.com/dnaeon/gru/resource"
+)
+
+// Event types
+const (
+	// EventLoaded is emitted for each resource after
+	// the catalog has been loaded
+	EventLoaded = "loaded"
+
+	// EventScheduled is emitted when a resource is
+	// scheduled for processing
+	EventScheduled = "scheduled"
+
+	// EventEvaluated is emitted when a resource has been evaluated
+	EventEvaluated = "evaluated"
+
+	// EventCreated is emitted when a resource has been created
+	EventCreated = "created"
+
+	// EventDeleted is emitted when a resource has been deleted
+	EventDeleted = "deleted"
+
+	// EventPropertySynced is emitted when an out of
+	// date resource property has been set
+	EventPropertySynced = "propertySynced"
+
+	// EventRefreshed is emitted when a resource has been refreshed
+	EventRefreshed = "refreshed"
+
+	// EventTriggerFired is emitted when a trigger
+	// of a resource has been executed
+	EventTriggerFired = "triggerFired"
+
+	// EventFailed is emitted when processing of a resource has failed
+	EventFailed = "failed"
+
+	// EventSkipped is emitted when a resource has been skipped
+	EventSkipped = "skipped"
+
+	// EventProcessed is emitted after a resource has been
+	// processed, regardless of whether it has failed or not
+	EventProcessed = "processed"
+)
+
+// Event type represents an event emitted during
+// loading and processing of a catalog.
+type Event struct {
+	// Type of the event
+	Type string `json:"type"`
+
+	// ID is the id of the resource, for which the event is emitted
+	ID string `json:"id"`
+
+	// Time is the time when the event was emitted
+	Time time.Time `json:"time"`
+
+	// DryRun specifies whether the event was emitted during a
+	// dry run, in which case no changes were actually made.
+	DryRun bool `json:"dryRun"`
+
+	// State is the evaluated state of the resource.
+	// Set for EventEvaluated events only.
+	State *resource.State `json:"state,omitempty"`
+
+	// Property is the name of the property, which was set.
+	// Set for EventPropertySynced events only.
+	Property string `json:"property,omitempty"`
+
+	// Subscribed is the id of the monitored resource, which
+	// caused a trigger to be executed.
+	// Set for EventTriggerFired events only.
+	Subscribed string `json:"subscribed,omitempty"`
+
+	// Item is the status of the processed resource.
+	// Set for EventFailed, EventSkipped and EventProcessed events only.
+	Item *StatusItem `json:"item,omitempty"`
+
+	// Err contains the error, which caused a resource
+	// to fail or to be skipped, if any.
+	Err error `json:"-"`
+}
+
+// Observer is an interface type for receiving events
+// emitted during loading and processing of a catalog.
+// Events are delivered to observers sequentially, so
+// observers do not need to synchronize access to their
+// own state, but they should return quickly in order to
+// avoid slowing down the processing of resources.
+type Observer interface {
+	// Observe is called for each emitted event
+	Observe(e *Event)
+}
+
+// ObserverFunc type is an adapter to allow the use of
+// ordinary functions as observers.
+type ObserverFunc func(e *Event)
+
+// Observe calls f(e)
+func (f ObserverFunc) Observe(e *Event) {
+	f(e)
+}
+
+// emit sends an event to the registered observers.
+func (c *Catalog) emit(e *Event) {
+	if len(c.config.Observers) == 0 {
+		return
+	}
+
+	e.Time = time.Now()
+	e.DryRun = c.config.DryRun
+
+	c.observerMu.Lock()
+	defer c.observerMu.Unlock()
+
+	for _, o := range c.config.Observers {
+		o.Observe(e)
+	}
+}
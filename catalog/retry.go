@@ -0,0 +1,74 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package catalog
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/dnaeon/backoff"
+	"github.com/dnaeon/gru/resource"
+)
+
+// retry executes f and retries it according to the retry policy of
+// the resource until f succeeds, the retries are exhausted or the
+// context is done. The number of retries is recorded in the status item.
+func (c *Catalog) retry(ctx context.Context, r resource.Resource, item *StatusItem, f func() error) error {
+	policy := r.RetryPolicy()
+
+	maxDelay := policy.MaxDelay
+	if maxDelay == 0 {
+		maxDelay = time.Duration(math.MaxInt64)
+	}
+
+	b := backoff.Backoff{
+		Min:    policy.Delay,
+		Max:    maxDelay,
+		Factor: policy.Factor,
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= policy.Retries || ctx.Err() != nil {
+			return err
+		}
+
+		var delay time.Duration
+		if policy.Delay > 0 {
+			delay = b.Duration()
+		}
+
+		item.Retries++
+		c.config.Logger.Printf("%s %s, retrying in %s\n", r.ID(), err, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package catalog
+
+import (
+	"fmt"
+
+	"github.com/dnaeon/gru/resource"
+)
+
+// selectResources returns the ids of the resources, which should be
+// processed according to the tags and resource ids from the
+// configuration. Resources selected by id or by tags are processed
+// along with the resources they depend on, unless any of them has one
+// of the skipped tags. If no selection has been made, the returned
+// map is nil, which means that all resources should be processed.
+func selectResources(collection resource.Collection, dependencies map[string][]string, config *Config) (map[string]bool, error) {
+	if len(config.Only) == 0 && len(config.Tags) == 0 && len(config.SkipTags) == 0 {
+		return nil, nil
+	}
+
+	var queue []string
+	if len(config.Only) == 0 && len(config.Tags) == 0 {
+		for id := range collection {
+			queue = append(queue, id)
+		}
+	}
+
+	for _, id := range config.Only {
+		if _, ok := collection[id]; !ok {
+			return nil, fmt.Errorf("resource %s does not exist", id)
+		}
+		queue = append(queue, id)
+	}
+
+	for id, r := range collection {
+		if hasAnyTag(r, config.Tags) {
+			queue = append(queue, id)
+		}
+	}
+
+	// Include the dependencies of the selected resources
+	selected := make(map[string]bool)
+	for len(queue) > 0 {
+		var id string
+		id, queue = queue[0], queue[1:]
+		if selected[id] {
+			continue
+		}
+
+		selected[id] = true
+		queue = append(queue, dependencies[id]...)
+	}
+
+	for id := range selected {
+		if hasAnyTag(collection[id], config.SkipTags) {
+			delete(selected, id)
+		}
+	}
+
+	return selected, nil
+}
+
+// hasAnyTag returns true if the resource has any of the given tags.
+func hasAnyTag(r resource.Resource, tags []string) bool {
+	for _, tag := range r.TagList() {
+		for _, t := range tags {
+			if tag == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isSelected returns true if the resource should be processed.
+func (c *Catalog) isSelected(id string) bool {
+	return c.selected == nil || c.selected[id]
+}
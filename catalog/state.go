@@ -0,0 +1,244 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package catalog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dnaeon/gru/resource"
+)
+
+// ModuleState type contains the resources, which were
+// managed by a module during it's last successful run.
+type ModuleState struct {
+	// Time when the state was saved
+	Time time.Time `json:"time"`
+
+	// Resources contains the managed resources
+	Resources []*resource.Spec `json:"resources"`
+}
+
+// localState type represents the contents of the local state file.
+type localState struct {
+	// Modules contains the state of each module,
+	// keyed by the module name
+	Modules map[string]*ModuleState `json:"modules"`
+}
+
+// readState reads the local state file. A missing
+// state file is treated as an empty state.
+func readState(path string) (*localState, error) {
+	state := &localState{
+		Modules: make(map[string]*ModuleState),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// writeState writes the local state file. The state is written
+// to a temporary file first, so that the state file is replaced
+// atomically. The state file is readable only by it's owner.
+func writeState(path string, state *localState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".gru-state")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// purgeRemoved adds the resources, which were managed by the
+// module during it's last successful run, but have been removed
+// from the module since then. The removed resources are added
+// to the catalog in an absent state.
+func (c *Catalog) purgeRemoved() error {
+	state, err := readState(c.config.StateFile)
+	if err != nil {
+		return err
+	}
+
+	previous, ok := state.Modules[c.module]
+	if !ok {
+		return nil
+	}
+
+	current := make(map[string]bool)
+	for _, r := range c.Unsorted {
+		current[r.ID()] = true
+	}
+
+	for _, spec := range previous.Resources {
+		id := spec.ID()
+		if current[id] {
+			continue
+		}
+
+		// Resources, which cannot be rebuilt from their type and
+		// name only, are reported and no longer kept in the state
+		r, err := purgeResource(spec)
+		if err == nil && r != nil {
+			err = r.Validate()
+		}
+		if err != nil {
+			c.config.Logger.Printf("%s has been removed from the module, but cannot be purged: %s\n", id, err)
+			continue
+		}
+
+		if r == nil {
+			continue
+		}
+
+		c.config.Logger.Printf("%s has been removed from the module, purging it\n", id)
+		c.purged[id] = true
+		c.Add(r)
+	}
+
+	return nil
+}
+
+// purgeResource rebuilds a removed resource in an absent state.
+// It returns nil if the resource cannot be absent. The resource is
+// rebuilt only from it's type and name, because the rest of the
+// fields may refer to things, which no longer exist, e.g. related
+// resources or source files removed along with the resource. The
+// properties of purged resources are not managed for that reason.
+func purgeResource(spec *resource.Spec) (resource.Resource, error) {
+	purge := &resource.Spec{
+		Type:     spec.Type,
+		Name:     spec.Name,
+		Provider: spec.Provider,
+		Fields:   make(map[string]json.RawMessage),
+	}
+
+	r, err := purge.Resource()
+	if err != nil {
+		return nil, err
+	}
+
+	absent := r.AbsentStates()
+	if len(absent) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(absent[0])
+	if err != nil {
+		return nil, err
+	}
+	purge.Fields["state"] = data
+
+	return purge.Resource()
+}
+
+// saveState saves the resources managed by the module into the
+// local state file. The state is saved only after a successful run
+// processing all resources from the module, so that resources which
+// could not be purged are purged during the next run.
+func (c *Catalog) saveState() error {
+	if c.config.StateFile == "" || c.config.DryRun || c.selected != nil {
+		return nil
+	}
+
+	if !c.isSuccessful() {
+		return nil
+	}
+
+	specs := make([]*resource.Spec, 0, len(c.sorted))
+	for _, node := range c.sorted {
+		if c.purged[node.Name] {
+			continue
+		}
+
+		spec, err := resource.DescribeResource(c.collection[node.Name])
+		if err != nil {
+			return err
+		}
+		specs = append(specs, spec)
+	}
+
+	state, err := readState(c.config.StateFile)
+	if err != nil {
+		return err
+	}
+
+	state.Modules[c.module] = &ModuleState{
+		Time:      time.Now(),
+		Resources: specs,
+	}
+
+	return writeState(c.config.StateFile, state)
+}
+
+// isSuccessful returns true if all resources
+// have been processed without errors.
+func (c *Catalog) isSuccessful() bool {
+	c.status.RLock()
+	defer c.status.RUnlock()
+
+	for _, node := range c.sorted {
+		item, ok := c.status.Items[node.Name]
+		if !ok || item.Err != nil {
+			return false
+		}
+	}
+
+	return true
+}
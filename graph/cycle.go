@@ -0,0 +1,239 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CircularDependencyError type is returned when a graph
+// contains circular dependencies. It contains the paths of the
+// cycles found in the graph.
+type CircularDependencyError struct {
+	// Cycles contains the node names of each cycle in the
+	// order of the edges, e.g. [A B] for A -> B -> A
+	Cycles [][]string
+}
+
+// Error implements the error interface
+func (e *CircularDependencyError) Error() string {
+	paths := make([]string, 0, len(e.Cycles))
+	for _, cycle := range e.Cycles {
+		paths = append(paths, CyclePath(cycle))
+	}
+
+	return fmt.Sprintf("%s: %s", ErrCircularDependency, strings.Join(paths, ", "))
+}
+
+// CyclePath returns the string representation of a cycle,
+// e.g. "A -> B -> A"
+func CyclePath(cycle []string) string {
+	if len(cycle) == 0 {
+		return ""
+	}
+
+	path := make([]string, 0, len(cycle)+1)
+	path = append(path, cycle...)
+	path = append(path, cycle[0])
+
+	return strings.Join(path, " -> ")
+}
+
+// StronglyConnected returns the strongly connected components of the
+// graph using Tarjan's algorithm. Nodes in each component are sorted
+// by name and the components are sorted by the name of their first node.
+// https://en.wikipedia.org/wiki/Tarjan%27s_strongly_connected_components_algorithm
+func (g *Graph) StronglyConnected() [][]*Node {
+	var components [][]*Node
+	var stack []*Node
+	index := 0
+	indices := make(map[*Node]int)
+	lowlink := make(map[*Node]int)
+	onStack := make(map[*Node]bool)
+
+	var connect func(n *Node)
+	connect = func(n *Node) {
+		indices[n] = index
+		lowlink[n] = index
+		index++
+		stack = append(stack, n)
+		onStack[n] = true
+
+		for _, edge := range n.Edges {
+			if _, ok := indices[edge]; !ok {
+				connect(edge)
+				if lowlink[edge] < lowlink[n] {
+					lowlink[n] = lowlink[edge]
+				}
+			} else if onStack[edge] && indices[edge] < lowlink[n] {
+				lowlink[n] = indices[edge]
+			}
+		}
+
+		// Pop the component if n is it's root node
+		if lowlink[n] == indices[n] {
+			var component []*Node
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == n {
+					break
+				}
+			}
+			sort.Sort(byName(component))
+			components = append(components, component)
+		}
+	}
+
+	for _, n := range g.sortedNodes() {
+		if _, ok := indices[n]; !ok {
+			connect(n)
+		}
+	}
+
+	sort.Sort(byFirstName(components))
+
+	return components
+}
+
+// Cycles returns a cycle for each strongly connected component of
+// the graph, which contains circular dependencies. Each cycle is
+// the shortest path from the first node of the component back to
+// itself. If the graph has no circular dependencies the result
+// is empty.
+func (g *Graph) Cycles() [][]*Node {
+	var cycles [][]*Node
+	for _, component := range g.StronglyConnected() {
+		start := component[0]
+		if len(component) == 1 && !hasEdge(start, start) {
+			continue
+		}
+
+		members := make(map[*Node]bool)
+		for _, n := range component {
+			members[n] = true
+		}
+
+		cycles = append(cycles, shortestCycle(start, members))
+	}
+
+	return cycles
+}
+
+// CheckCycles returns a CircularDependencyError describing the
+// cycles of the graph, or nil if there are no circular dependencies.
+func (g *Graph) CheckCycles() error {
+	cycles := g.Cycles()
+	if len(cycles) == 0 {
+		return nil
+	}
+
+	err := &CircularDependencyError{
+		Cycles: make([][]string, 0, len(cycles)),
+	}
+
+	for _, cycle := range cycles {
+		names := make([]string, 0, len(cycle))
+		for _, n := range cycle {
+			names = append(names, n.Name)
+		}
+		err.Cycles = append(err.Cycles, names)
+	}
+
+	return err
+}
+
+// shortestCycle finds the shortest path from the start node back to
+// itself, using only the given nodes, by performing a breadth-first search.
+func shortestCycle(start *Node, members map[*Node]bool) []*Node {
+	parent := make(map[*Node]*Node)
+	queue := []*Node{start}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, edge := range n.Edges {
+			if !members[edge] {
+				continue
+			}
+
+			if edge == start {
+				// Walk back to the start node to build the path
+				var path []*Node
+				for p := n; p != start; p = parent[p] {
+					path = append([]*Node{p}, path...)
+				}
+				return append([]*Node{start}, path...)
+			}
+
+			if _, ok := parent[edge]; !ok {
+				parent[edge] = n
+				queue = append(queue, edge)
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasEdge checks if there is an edge between two nodes
+func hasEdge(from, to *Node) bool {
+	for _, edge := range from.Edges {
+		if edge == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortedNodes returns the nodes of the graph sorted by name
+func (g *Graph) sortedNodes() []*Node {
+	nodes := make([]*Node, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Sort(byName(nodes))
+
+	return nodes
+}
+
+// byName sorts nodes by their name
+type byName []*Node
+
+func (n byName) Len() int           { return len(n) }
+func (n byName) Swap(i, j int)      { n[i], n[j] = n[j], n[i] }
+func (n byName) Less(i, j int) bool { return n[i].Name < n[j].Name }
+
+// byFirstName sorts components by the name of their first node
+type byFirstName [][]*Node
+
+func (c byFirstName) Len() int           { return len(c) }
+func (c byFirstName) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byFirstName) Less(i, j int) bool { return c[i][0].Name < c[j][0].Name }
@@ -0,0 +1,254 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package graph
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Supported output formats for the graph
+const (
+	// FormatDot is the Graphviz DOT format
+	FormatDot = "dot"
+
+	// FormatJSON is the JSON format
+	FormatJSON = "json"
+
+	// FormatMermaid is the Mermaid flowchart format
+	FormatMermaid = "mermaid"
+
+	// FormatGraphML is the GraphML format
+	FormatGraphML = "graphml"
+)
+
+// Formats contains the supported output formats
+var Formats = []string{FormatDot, FormatJSON, FormatMermaid, FormatGraphML}
+
+// Write writes the graph using the given output format
+func (g *Graph) Write(name, format string, w io.Writer) error {
+	switch format {
+	case FormatDot:
+		g.AsDot(name, w)
+	case FormatJSON:
+		return g.AsJSON(name, w)
+	case FormatMermaid:
+		g.AsMermaid(name, w)
+	case FormatGraphML:
+		return g.AsGraphML(name, w)
+	default:
+		return fmt.Errorf("Unknown graph format %q", format)
+	}
+
+	return nil
+}
+
+// jsonNode is the JSON representation of a node
+type jsonNode struct {
+	Name       string            `json:"name"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Edges      []string          `json:"edges"`
+}
+
+// jsonGraph is the JSON representation of a graph
+type jsonGraph struct {
+	Name  string     `json:"name"`
+	Nodes []jsonNode `json:"nodes"`
+}
+
+// AsJSON generates a JSON representation for the graph.
+// Nodes and their edges are sorted by name.
+func (g *Graph) AsJSON(name string, w io.Writer) error {
+	out := jsonGraph{
+		Name:  name,
+		Nodes: make([]jsonNode, 0, len(g.Nodes)),
+	}
+
+	for _, node := range g.sortedNodes() {
+		n := jsonNode{
+			Name:       node.Name,
+			Attributes: node.Attributes,
+			Edges:      make([]string, 0, len(node.Edges)),
+		}
+		for _, edge := range sortedEdges(node) {
+			n.Edges = append(n.Edges, edge.Name)
+		}
+		out.Nodes = append(out.Nodes, n)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+
+	return err
+}
+
+// AsMermaid generates a Mermaid flowchart representation for the graph
+// https://mermaid-js.github.io/mermaid/#/flowchart
+func (g *Graph) AsMermaid(name string, w io.Writer) {
+	nodes := g.sortedNodes()
+
+	// Node names may contain characters, which are not valid in
+	// Mermaid ids, so use generated ids and the names as labels
+	ids := make(map[*Node]string)
+	for i, node := range nodes {
+		ids[node] = fmt.Sprintf("n%d", i)
+	}
+
+	fmt.Fprintf(w, "---\ntitle: %s\n---\n", name)
+	fmt.Fprintf(w, "flowchart LR\n")
+	for _, node := range nodes {
+		label := strings.Join(node.labelLines(), "<br/>")
+		fmt.Fprintf(w, "\t%s[\"%s\"]\n", ids[node], mermaidEscape(label))
+	}
+
+	for _, node := range nodes {
+		for _, edge := range sortedEdges(node) {
+			id, ok := ids[edge]
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(w, "\t%s --> %s\n", ids[node], id)
+		}
+	}
+}
+
+// mermaidEscape escapes the characters, which cannot be used
+// in Mermaid labels
+func mermaidEscape(s string) string {
+	return strings.Replace(s, `"`, "#quot;", -1)
+}
+
+// graphMLKey is a GraphML attribute definition
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+// graphMLData is a GraphML attribute value
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// graphMLNode is a GraphML node
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+// graphMLEdge is a GraphML edge
+type graphMLEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+// graphML is a GraphML document
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+// AsGraphML generates a GraphML representation for the graph
+// http://graphml.graphdrawing.org/
+func (g *Graph) AsGraphML(name string, w io.Writer) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+	}
+	doc.Graph.ID = name
+	doc.Graph.EdgeDefault = "directed"
+
+	// Define a key for each node attribute
+	keys := make(map[string]bool)
+	nodes := g.sortedNodes()
+	for _, node := range nodes {
+		for key := range node.Attributes {
+			keys[key] = true
+		}
+	}
+
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	for _, key := range names {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: key, For: "node", Name: key, Type: "string"})
+	}
+
+	for _, node := range nodes {
+		n := graphMLNode{ID: node.Name}
+		for _, key := range node.attributeKeys() {
+			n.Data = append(n.Data, graphMLData{Key: key, Value: node.Attributes[key]})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, n)
+
+		for _, edge := range sortedEdges(node) {
+			doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: node.Name, Target: edge.Name})
+		}
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+
+	return err
+}
+
+// sortedEdges returns the unique edges of a node sorted by name
+func sortedEdges(node *Node) []*Node {
+	edges := make([]*Node, 0, len(node.Edges))
+	seen := make(map[*Node]bool)
+	for _, edge := range node.Edges {
+		if !seen[edge] {
+			seen[edge] = true
+			edges = append(edges, edge)
+		}
+	}
+	sort.Sort(byName(edges))
+
+	return edges
+}
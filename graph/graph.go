@@ -29,9 +29,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
-
-	mapset "github.com/deckarep/golang-set"
 )
 
 // ErrCircularDependency is returned when the graph cannot be
@@ -82,58 +81,181 @@ func (g *Graph) GetNode(name string) (*Node, bool) {
 // If the graph cannot be sorted in case of circular dependencies,
 // then the result will contain the remaining nodes from the graph,
 // which are the ones causing the circular dependency.
+//
+// Sorting does not modify the graph, so it can be sorted
+// any number of times.
 func (g *Graph) Sort() ([]*Node, error) {
 	var sorted []*Node
 
-	// Iteratively find and remove nodes from the graph which have no edges.
-	// If at some point there are still nodes in the graph and we cannot find
-	// nodes without edges, that means we have a circular dependency
-	for len(g.Nodes) > 0 {
-		// Contains the ready nodes, which have no edges to other nodes
-		ready := mapset.NewSet()
-
-		// Find the nodes with no edges
-		for _, node := range g.Nodes {
-			if len(node.Edges) == 0 {
-				ready.Add(node)
-			}
+	levels, err := g.Levels()
+	for _, level := range levels {
+		sorted = append(sorted, level...)
+	}
+
+	if err != nil {
+		// The nodes which were not sorted are the ones
+		// causing the circular dependency.
+		seen := make(map[*Node]bool)
+		for _, n := range sorted {
+			seen[n] = true
 		}
 
-		// If there aren't any ready nodes, then we have a cicular dependency
-		if ready.Cardinality() == 0 {
-			// The remaining nodes in the graph are the ones causing the
-			// circular dependency.
-			var remaining []*Node
-			for _, n := range g.Nodes {
+		var remaining []*Node
+		for _, n := range g.sortedNodes() {
+			if !seen[n] {
 				remaining = append(remaining, n)
 			}
-			return remaining, ErrCircularDependency
 		}
+		return remaining, err
+	}
+
+	return sorted, nil
+}
+
+// Levels performs a topological sort of the graph and groups the
+// nodes in levels. The nodes in each level depend only on nodes from
+// the previous levels, so the nodes within a level can be processed
+// in parallel. Nodes in each level are sorted by name.
+//
+// If the graph contains circular dependencies the result contains
+// the levels, which could be sorted, and ErrCircularDependency.
+func (g *Graph) Levels() ([][]*Node, error) {
+	var levels [][]*Node
+
+	// Number of edges to nodes which are not sorted yet and
+	// the nodes which have edges to a given node
+	pending := make(map[*Node]int)
+	dependents := make(map[*Node][]*Node)
+	for _, node := range g.Nodes {
+		pending[node] = len(node.Edges)
+		for _, edge := range node.Edges {
+			dependents[edge] = append(dependents[edge], node)
+		}
+	}
 
-		// Remove the ready nodes and add them to the sorted result
-		for item := range ready.Iter() {
-			node := item.(*Node)
-			delete(g.Nodes, node.Name)
-			sorted = append(sorted, node)
+	// Find the nodes with no edges
+	var ready []*Node
+	for _, node := range g.Nodes {
+		if pending[node] == 0 {
+			ready = append(ready, node)
 		}
+	}
 
-		// Remove ready nodes from the remaining node edges as well
-		for _, node := range g.Nodes {
-			// Add the remaining nodes in a set
-			currentEdgeSet := mapset.NewSet()
-			for _, edge := range node.Edges {
-				currentEdgeSet.Add(edge)
+	count := 0
+	for len(ready) > 0 {
+		sort.Sort(byName(ready))
+		levels = append(levels, ready)
+		count += len(ready)
+
+		// Nodes are ready once all of their edges have been sorted
+		var next []*Node
+		for _, node := range ready {
+			for _, dependent := range dependents[node] {
+				pending[dependent]--
+				if pending[dependent] == 0 {
+					next = append(next, dependent)
+				}
 			}
+		}
+		ready = next
+	}
 
-			newEdgeSet := currentEdgeSet.Difference(ready)
-			node.Edges = make([]*Node, 0)
-			for edge := range newEdgeSet.Iter() {
-				node.Edges = append(node.Edges, edge.(*Node))
+	// If there are nodes which were not sorted,
+	// then we have a circular dependency
+	if count < len(g.Nodes) {
+		return levels, ErrCircularDependency
+	}
+
+	return levels, nil
+}
+
+// Descendants returns the nodes, which are reachable from the given
+// node by following it's edges. The result is sorted by name.
+func (g *Graph) Descendants(node *Node) []*Node {
+	return reachable(node, func(n *Node) []*Node {
+		return n.Edges
+	})
+}
+
+// Ancestors returns the nodes of the graph from which the given
+// node is reachable. The result is sorted by name.
+func (g *Graph) Ancestors(node *Node) []*Node {
+	dependents := make(map[*Node][]*Node)
+	for _, n := range g.Nodes {
+		for _, edge := range n.Edges {
+			dependents[edge] = append(dependents[edge], n)
+		}
+	}
+
+	return reachable(node, func(n *Node) []*Node {
+		return dependents[n]
+	})
+}
+
+// TransitiveReduction creates a new graph with the same nodes and
+// reachability as the graph, but without the edges which are implied
+// by other paths in the graph, e.g. A -> C is removed if the graph
+// contains A -> B and B -> C.
+// https://en.wikipedia.org/wiki/Transitive_reduction
+//
+// Transitive reduction is unique only for acyclic graphs, so an
+// error is returned if the graph contains circular dependencies.
+func (g *Graph) TransitiveReduction() (*Graph, error) {
+	if err := g.CheckCycles(); err != nil {
+		return nil, err
+	}
+
+	reduced := New()
+	nodes := make(map[string]*Node)
+	for _, n := range g.Nodes {
+		node := copyNode(n)
+		nodes[n.Name] = node
+		reduced.AddNode(node)
+	}
+
+	for _, n := range g.sortedNodes() {
+		// Nodes reachable through the other edges of the node
+		implied := make(map[*Node]bool)
+		for _, edge := range n.Edges {
+			for _, d := range g.Descendants(edge) {
+				implied[d] = true
 			}
 		}
+
+		seen := make(map[*Node]bool)
+		for _, edge := range n.Edges {
+			if implied[edge] || seen[edge] {
+				continue
+			}
+			seen[edge] = true
+			reduced.AddEdge(nodes[n.Name], nodes[edge.Name])
+		}
 	}
 
-	return sorted, nil
+	return reduced, nil
+}
+
+// reachable returns the nodes reachable from the given node,
+// using next to find the adjacent nodes. The result is sorted by name.
+func reachable(node *Node, next func(n *Node) []*Node) []*Node {
+	var result []*Node
+	visited := map[*Node]bool{node: true}
+	queue := []*Node{node}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, adjacent := range next(n) {
+			if visited[adjacent] {
+				continue
+			}
+			visited[adjacent] = true
+			result = append(result, adjacent)
+			queue = append(queue, adjacent)
+		}
+	}
+	sort.Sort(byName(result))
+
+	return result
 }
 
 // AsDot generates a DOT representation for the graph
@@ -145,9 +267,13 @@ func (g *Graph) AsDot(name string, w io.Writer) {
 	fmt.Fprintf(w, "\tnode [shape=box];\n")
 	fmt.Fprintf(w, "\tedge [style=filled];\n")
 
-	for _, node := range g.Nodes {
+	for _, node := range g.sortedNodes() {
+		if len(node.Attributes) > 0 {
+			fmt.Fprintf(w, "\t%q [label=%q];\n", node.Name, strings.Join(node.labelLines(), "\n"))
+		}
+
 		var edges []string
-		for _, edge := range node.Edges {
+		for _, edge := range sortedEdges(node) {
 			edges = append(edges, fmt.Sprintf("%q", edge.Name))
 		}
 
@@ -168,7 +294,7 @@ func (g *Graph) Reversed() *Graph {
 	// Create a map of the graph nodes
 	nodes := make(map[string]*Node)
 	for _, n := range g.Nodes {
-		node := NewNode(n.Name)
+		node := copyNode(n)
 		nodes[n.Name] = node
 		reversed.AddNode(node)
 	}
@@ -26,7 +26,11 @@
 package graph
 
 import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -87,6 +91,15 @@ func TestWorkingGraph(t *testing.T) {
 	if !reflect.DeepEqual(wantSorted, gotSorted) {
 		t.Errorf("Want %q, got %q graph", wantSorted, gotSorted)
 	}
+
+	// Sorting must not modify the graph
+	if len(g.Nodes) != len(nodeNames) {
+		t.Errorf("want %d nodes after sort, got %d", len(nodeNames), len(g.Nodes))
+	}
+
+	if _, err := g.Sort(); err != nil {
+		t.Errorf("want graph to be sorted again, got %s", err)
+	}
 }
 
 func TestCircularGraph(t *testing.T) {
@@ -129,3 +142,246 @@ func TestCircularGraph(t *testing.T) {
 		t.Errorf("want a circular dependency error, got %s", err)
 	}
 }
+
+func TestCycles(t *testing.T) {
+	g := New()
+
+	nodes := make(map[string]*Node)
+	for _, name := range []string{"A", "B", "C", "D", "E", "F", "G"} {
+		n := NewNode(name)
+		nodes[name] = n
+		g.AddNode(n)
+	}
+
+	// Connect the nodes in the graph
+	//
+	// A -> B
+	// B -> C
+	// C -> A  <- Circular dependency here
+	// C -> D
+	// D -> E
+	// E -> D  <- Circular dependency here
+	// F -> F  <- Circular dependency here
+	// G -> A
+	//
+	g.AddEdge(nodes["A"], nodes["B"])
+	g.AddEdge(nodes["B"], nodes["C"])
+	g.AddEdge(nodes["C"], nodes["A"], nodes["D"])
+	g.AddEdge(nodes["D"], nodes["E"])
+	g.AddEdge(nodes["E"], nodes["D"])
+	g.AddEdge(nodes["F"], nodes["F"])
+	g.AddEdge(nodes["G"], nodes["A"])
+
+	err := g.CheckCycles()
+	cycleErr, ok := err.(*CircularDependencyError)
+	if !ok {
+		t.Fatalf("want a circular dependency error, got %v", err)
+	}
+
+	want := [][]string{
+		{"A", "B", "C"},
+		{"D", "E"},
+		{"F"},
+	}
+
+	if !reflect.DeepEqual(want, cycleErr.Cycles) {
+		t.Errorf("want %q cycles, got %q", want, cycleErr.Cycles)
+	}
+
+	wantMsg := "Circular dependency found in graph: A -> B -> C -> A, D -> E -> D, F -> F"
+	if err.Error() != wantMsg {
+		t.Errorf("want error %q, got %q", wantMsg, err.Error())
+	}
+}
+
+func TestNoCycles(t *testing.T) {
+	g := New()
+
+	a := NewNode("A")
+	b := NewNode("B")
+	g.AddNode(a, b)
+	g.AddEdge(a, b)
+
+	if err := g.CheckCycles(); err != nil {
+		t.Errorf("want no circular dependency error, got %s", err)
+	}
+}
+
+// newDiamondGraph creates the following graph
+//
+// A -> B
+// A -> C
+// A -> D
+// B -> D
+// C -> D
+// E
+func newDiamondGraph() (*Graph, map[string]*Node) {
+	g := New()
+
+	nodes := make(map[string]*Node)
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		n := NewNode(name)
+		nodes[name] = n
+		g.AddNode(n)
+	}
+
+	g.AddEdge(nodes["A"], nodes["B"], nodes["C"], nodes["D"])
+	g.AddEdge(nodes["B"], nodes["D"])
+	g.AddEdge(nodes["C"], nodes["D"])
+
+	return g, nodes
+}
+
+func nodeNames(nodes []*Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+
+	return names
+}
+
+func TestLevels(t *testing.T) {
+	g, _ := newDiamondGraph()
+
+	levels, err := g.Levels()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"D", "E"},
+		{"B", "C"},
+		{"A"},
+	}
+
+	var got [][]string
+	for _, level := range levels {
+		got = append(got, nodeNames(level))
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q levels, got %q", want, got)
+	}
+}
+
+func TestAncestorsDescendants(t *testing.T) {
+	g, nodes := newDiamondGraph()
+
+	tests := []struct {
+		node        string
+		ancestors   []string
+		descendants []string
+	}{
+		{"A", []string{}, []string{"B", "C", "D"}},
+		{"B", []string{"A"}, []string{"D"}},
+		{"D", []string{"A", "B", "C"}, []string{}},
+		{"E", []string{}, []string{}},
+	}
+
+	for _, test := range tests {
+		ancestors := nodeNames(g.Ancestors(nodes[test.node]))
+		if !reflect.DeepEqual(test.ancestors, ancestors) {
+			t.Errorf("want %q ancestors of %s, got %q", test.ancestors, test.node, ancestors)
+		}
+
+		descendants := nodeNames(g.Descendants(nodes[test.node]))
+		if !reflect.DeepEqual(test.descendants, descendants) {
+			t.Errorf("want %q descendants of %s, got %q", test.descendants, test.node, descendants)
+		}
+	}
+}
+
+func TestTransitiveReduction(t *testing.T) {
+	g, _ := newDiamondGraph()
+
+	reduced, err := g.TransitiveReduction()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"A": {"B", "C"},
+		"B": {"D"},
+		"C": {"D"},
+		"D": {},
+		"E": {},
+	}
+
+	got := make(map[string][]string)
+	for name, n := range reduced.Nodes {
+		got[name] = nodeNames(n.Edges)
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %q reduced graph, got %q", want, got)
+	}
+
+	// The original graph must be left intact
+	if a := g.Nodes["A"]; len(a.Edges) != 3 {
+		t.Errorf("want 3 edges for A in the original graph, got %d", len(a.Edges))
+	}
+
+	// Graphs with circular dependencies cannot be reduced
+	g.AddEdge(g.Nodes["D"], g.Nodes["A"])
+	if _, err := g.TransitiveReduction(); err == nil {
+		t.Error("want a circular dependency error, got nil")
+	}
+}
+
+func TestFormats(t *testing.T) {
+	g, nodes := newDiamondGraph()
+	nodes["A"].Attributes["type"] = "pkg"
+
+	// Every format must be generated without errors
+	for _, format := range Formats {
+		var buf bytes.Buffer
+		if err := g.Write("resources", format, &buf); err != nil {
+			t.Errorf("format %s: %s", format, err)
+		}
+	}
+
+	if err := g.Write("resources", "unknown", &bytes.Buffer{}); err == nil {
+		t.Error("want an error for unknown format, got nil")
+	}
+
+	var buf bytes.Buffer
+	if err := g.AsJSON("resources", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var out jsonGraph
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	wantNode := jsonNode{
+		Name:       "A",
+		Attributes: map[string]string{"type": "pkg"},
+		Edges:      []string{"B", "C", "D"},
+	}
+	if len(out.Nodes) != 5 || !reflect.DeepEqual(wantNode, out.Nodes[0]) {
+		t.Errorf("want first node %v, got %v", wantNode, out.Nodes)
+	}
+
+	buf.Reset()
+	if err := g.AsGraphML("resources", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Keys) != 1 || len(doc.Graph.Nodes) != 5 || len(doc.Graph.Edges) != 5 {
+		t.Errorf("want 1 key, 5 nodes and 5 edges, got %d, %d and %d", len(doc.Keys), len(doc.Graph.Nodes), len(doc.Graph.Edges))
+	}
+
+	buf.Reset()
+	g.AsMermaid("resources", &buf)
+	for _, want := range []string{"flowchart LR", `n0["A<br/>type=pkg"]`, "n0 --> n1", "n1 --> n3"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("want %q in mermaid output, got:\n%s", want, buf.String())
+		}
+	}
+}
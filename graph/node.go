@@ -25,6 +25,11 @@
 
 package graph
 
+import (
+	"fmt"
+	"sort"
+)
+
 // Node represents a single node in the graph
 type Node struct {
 	// Name of the node
@@ -32,14 +37,52 @@ type Node struct {
 
 	// Edges to other nodes in the graph
 	Edges []*Node
+
+	// Attributes contains additional information about the node,
+	// which is included when generating the graph representation
+	Attributes map[string]string
 }
 
 // NewNode creates a new node with the given name
 func NewNode(name string) *Node {
 	n := &Node{
-		Name:  name,
-		Edges: make([]*Node, 0),
+		Name:       name,
+		Edges:      make([]*Node, 0),
+		Attributes: make(map[string]string),
+	}
+
+	return n
+}
+
+// copyNode creates a new node with the name and attributes
+// of the given node, but without any edges
+func copyNode(node *Node) *Node {
+	n := NewNode(node.Name)
+	for k, v := range node.Attributes {
+		n.Attributes[k] = v
 	}
 
 	return n
 }
+
+// attributeKeys returns the sorted attribute names of the node
+func (n *Node) attributeKeys() []string {
+	keys := make([]string, 0, len(n.Attributes))
+	for k := range n.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// labelLines returns the name of the node followed by
+// it's attributes, which are used as the label of the node
+func (n *Node) labelLines() []string {
+	lines := []string{n.Name}
+	for _, key := range n.attributeKeys() {
+		lines = append(lines, fmt.Sprintf("%s=%s", key, n.Attributes[key]))
+	}
+
+	return lines
+}
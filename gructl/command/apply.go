@@ -26,9 +26,12 @@
 package command
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/dnaeon/gru/catalog"
 	"github.com/urfave/cli"
@@ -49,6 +52,16 @@ func NewApplyCommand() cli.Command {
 				Usage:  "path/url to the site repo",
 				EnvVar: "GRU_SITEREPO",
 			},
+			cli.StringFlag{
+				Name:  "catalog",
+				Value: "",
+				Usage: "apply a compiled catalog instead of a module",
+			},
+			cli.StringFlag{
+				Name:  "state-file",
+				Value: "",
+				Usage: "path to the local state file used for purging removed resources",
+			},
 			cli.BoolFlag{
 				Name:  "dry-run",
 				Usage: "just report what would be done, instead of doing it",
@@ -58,6 +71,36 @@ func NewApplyCommand() cli.Command {
 				Usage: "number of goroutines used for concurrent processing",
 				Value: runtime.NumCPU(),
 			},
+			cli.DurationFlag{
+				Name:  "deadline",
+				Usage: "maximum time processing of the catalog may take",
+				Value: time.Duration(0),
+			},
+			cli.StringFlag{
+				Name:  "report",
+				Value: "",
+				Usage: "write a JSON report of the processed resources to file",
+			},
+			cli.StringFlag{
+				Name:  "failure-policy",
+				Value: catalog.FailurePolicyContinue,
+				Usage: "how to proceed on failures: continue, fail-fast or fail-subtree",
+			},
+			cli.StringFlag{
+				Name:  "tags",
+				Value: "",
+				Usage: "comma-separated list of tags to process",
+			},
+			cli.StringFlag{
+				Name:  "skip-tags",
+				Value: "",
+				Usage: "comma-separated list of tags to skip",
+			},
+			cli.StringFlag{
+				Name:  "only",
+				Value: "",
+				Usage: "comma-separated list of resource ids to process",
+			},
 		},
 	}
 
@@ -66,7 +109,8 @@ func NewApplyCommand() cli.Command {
 
 // Executes the "apply" command
 func execApplyCommand(c *cli.Context) error {
-	if len(c.Args()) < 1 {
+	module := c.Args().First()
+	if module == "" && c.String("catalog") == "" {
 		return cli.NewExitError(errNoModuleName.Error(), 64)
 	}
 
@@ -81,12 +125,19 @@ func execApplyCommand(c *cli.Context) error {
 	logger := log.New(os.Stdout, "", log.LstdFlags)
 
 	config := &catalog.Config{
-		Module:      c.Args()[0],
-		DryRun:      c.Bool("dry-run"),
-		Logger:      logger,
-		SiteRepo:    c.String("siterepo"),
-		L:           L,
-		Concurrency: concurrency,
+		Module:        module,
+		Catalog:       c.String("catalog"),
+		StateFile:     c.String("state-file"),
+		DryRun:        c.Bool("dry-run"),
+		Logger:        logger,
+		SiteRepo:      c.String("siterepo"),
+		L:             L,
+		Concurrency:   concurrency,
+		Timeout:       c.Duration("deadline"),
+		Tags:          parseList(c.String("tags")),
+		SkipTags:      parseList(c.String("skip-tags")),
+		Only:          parseList(c.String("only")),
+		FailurePolicy: c.String("failure-policy"),
 	}
 
 	katalog := catalog.New(config)
@@ -99,5 +150,16 @@ func execApplyCommand(c *cli.Context) error {
 	status := katalog.Run()
 	status.Summary(logger)
 
+	if path := c.String("report"); path != "" {
+		data, err := json.MarshalIndent(status.Report(), "", "  ")
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,108 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/dnaeon/gru/catalog"
+	"github.com/urfave/cli"
+	"github.com/yuin/gopher-lua"
+)
+
+// NewCompileCommand creates a new sub-command for
+// compiling a module to a serialized catalog
+func NewCompileCommand() cli.Command {
+	cmd := cli.Command{
+		Name:   "compile",
+		Usage:  "compile module to a catalog, which can be applied without Lua",
+		Action: execCompileCommand,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "siterepo",
+				Value:  "",
+				Usage:  "path/url to the site repo",
+				EnvVar: "GRU_SITEREPO",
+			},
+			cli.StringFlag{
+				Name:  "output, o",
+				Value: "",
+				Usage: "write the compiled catalog to file instead of stdout",
+			},
+		},
+	}
+
+	return cmd
+}
+
+// Executes the "compile" command
+func execCompileCommand(c *cli.Context) error {
+	if len(c.Args()) < 1 {
+		return cli.NewExitError(errNoModuleName.Error(), 64)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	config := &catalog.Config{
+		Module:   c.Args()[0],
+		DryRun:   true,
+		Logger:   log.New(os.Stderr, "", log.LstdFlags),
+		SiteRepo: c.String("siterepo"),
+		L:        L,
+	}
+
+	katalog := catalog.New(config)
+	if err := katalog.Load(); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	compiled, err := katalog.Compile()
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	data, err := json.MarshalIndent(compiled, "", "  ")
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	path := c.String("output")
+	if path == "" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+	} else {
+		err = ioutil.WriteFile(path, data, 0600)
+	}
+
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
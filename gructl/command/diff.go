@@ -0,0 +1,186 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/dnaeon/gru/catalog"
+	"github.com/dnaeon/gru/utils"
+	"github.com/urfave/cli"
+	"github.com/yuin/gopher-lua"
+)
+
+// NewDiffCommand creates a new sub-command for comparing
+// the catalogs of a module from two environments or commits
+func NewDiffCommand() cli.Command {
+	cmd := cli.Command{
+		Name:   "diff",
+		Usage:  "compare module catalogs between environments or commits",
+		Action: execDiffCommand,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:   "siterepo",
+				Value:  "",
+				Usage:  "path/url to the site repo",
+				EnvVar: "GRU_SITEREPO",
+			},
+			cli.StringFlag{
+				Name:  "from",
+				Value: "",
+				Usage: "environment or commit to compare from",
+			},
+			cli.StringFlag{
+				Name:  "to",
+				Value: "",
+				Usage: "environment or commit to compare to",
+			},
+			cli.BoolFlag{
+				Name:  "json",
+				Usage: "display the differences in JSON format",
+			},
+		},
+	}
+
+	return cmd
+}
+
+// Executes the "diff" command
+func execDiffCommand(c *cli.Context) error {
+	if len(c.Args()) < 1 {
+		return cli.NewExitError(errNoModuleName.Error(), 64)
+	}
+
+	siteRepo := c.String("siterepo")
+	if siteRepo == "" {
+		return cli.NewExitError(errNoSiteRepo.Error(), 64)
+	}
+
+	from, to := c.String("from"), c.String("to")
+	if from == "" || to == "" {
+		return cli.NewExitError(errNoRevision.Error(), 64)
+	}
+
+	dir, err := ioutil.TempDir("", "gru-diff")
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+	defer os.RemoveAll(dir)
+
+	module := c.Args()[0]
+	fromCatalog, err := describeRevision(siteRepo, filepath.Join(dir, "from"), from, module)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	toCatalog, err := describeRevision(siteRepo, filepath.Join(dir, "to"), to, module)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	diff := catalog.Diff(fromCatalog, toCatalog)
+	if c.Bool("json") {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	for _, d := range diff {
+		switch d.Change {
+		case catalog.ChangeAdded:
+			fmt.Printf("+ %s\n", d.ID)
+		case catalog.ChangeRemoved:
+			fmt.Printf("- %s\n", d.ID)
+		case catalog.ChangeModified:
+			fmt.Printf("~ %s\n", d.ID)
+			for _, f := range d.Fields {
+				fmt.Printf("    %s: %s -> %s\n", f.Name, fieldValue(f.Old), fieldValue(f.New))
+			}
+		}
+	}
+
+	fmt.Printf("%d resource(s) differ between %s and %s\n", len(diff), from, to)
+
+	return nil
+}
+
+// describeRevision checks out the given environment or commit
+// of the site repo and describes the catalog of the module.
+// Source files are resolved from the checked out revision, so
+// that changes to them show up as changes of the file content.
+func describeRevision(upstream, path, revision, module string) (*catalog.Compiled, error) {
+	repo, err := utils.NewGitRepo(path, upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	if out, err := repo.Clone(); err != nil {
+		return nil, fmt.Errorf("unable to clone site repo: %s", out)
+	}
+
+	// Environments are branches in the site repo, which
+	// after cloning exist only as remote branches
+	if _, err := repo.CheckoutDetached(revision); err != nil {
+		if out, err := repo.CheckoutDetached("origin/" + revision); err != nil {
+			return nil, fmt.Errorf("unable to checkout %s: %s", revision, out)
+		}
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	config := &catalog.Config{
+		Module:   filepath.Join(path, module),
+		DryRun:   true,
+		Logger:   log.New(ioutil.Discard, "", log.LstdFlags),
+		SiteRepo: path,
+		L:        L,
+	}
+
+	katalog := catalog.New(config)
+	if err := katalog.Load(); err != nil {
+		return nil, fmt.Errorf("unable to load %s at %s: %s", module, revision, err)
+	}
+
+	return katalog.Describe()
+}
+
+// fieldValue returns the string representation of a serialized field
+func fieldValue(data json.RawMessage) string {
+	if data == nil {
+		return "(unset)"
+	}
+
+	return string(data)
+}
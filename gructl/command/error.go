@@ -36,4 +36,5 @@ var (
 	errNoTask            = errors.New("Missing task uuid")
 	errNoModuleName      = errors.New("Missing module name")
 	errNoSiteRepo        = errors.New("Missing site repo")
+	errNoRevision        = errors.New("Missing environment or commit to compare")
 )
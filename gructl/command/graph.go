@@ -26,8 +26,13 @@
 package command
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/dnaeon/gru/catalog"
 	"github.com/dnaeon/gru/graph"
@@ -50,6 +55,25 @@ func NewGraphCommand() cli.Command {
 				Usage:  "path/url to the site repo",
 				EnvVar: "GRU_SITEREPO",
 			},
+			cli.StringFlag{
+				Name:  "format",
+				Value: graph.FormatDot,
+				Usage: "output format - dot, json, mermaid or graphml",
+			},
+			cli.StringFlag{
+				Name:  "graph",
+				Value: "resources",
+				Usage: "graph to generate - resources, reversed or reduced",
+			},
+			cli.StringFlag{
+				Name:  "impact",
+				Value: "",
+				Usage: "list the resources and triggers affected by a change of the resource, or graph them if a format is given",
+			},
+			cli.BoolFlag{
+				Name:  "annotate",
+				Usage: "annotate nodes with resource type, concurrency and subscriptions",
+			},
 		},
 	}
 
@@ -62,6 +86,11 @@ func execGraphCommand(c *cli.Context) error {
 		return cli.NewExitError(errNoModuleName.Error(), 64)
 	}
 
+	format := c.String("format")
+	if !isValidFormat(format) {
+		return cli.NewExitError(fmt.Sprintf("Unknown graph format %q", format), 64)
+	}
+
 	L := lua.NewState()
 	defer L.Close()
 
@@ -85,21 +114,197 @@ func execGraphCommand(c *cli.Context) error {
 		return cli.NewExitError(err.Error(), 1)
 	}
 
+	if id := c.String("impact"); id != "" {
+		// The impact is listed as text unless a format is given
+		if !c.IsSet("format") {
+			format = ""
+		}
+		return showImpact(collection, id, format)
+	}
+
 	g, err := collection.DependencyGraph()
 	if err != nil {
 		return cli.NewExitError(err.Error(), 1)
 	}
 
-	g.AsDot("resources", os.Stdout)
-	g.Reversed().AsDot("reversed", os.Stdout)
+	if c.Bool("annotate") {
+		annotate(g, collection)
+	}
 
-	sorted, err := g.Sort()
-	if err == graph.ErrCircularDependency {
+	// Display only the paths of the cycles
+	if err := g.CheckCycles(); err != nil {
 		circular := graph.New()
-		circular.AddNode(sorted...)
-		circular.AsDot("circular", os.Stdout)
-		return cli.NewExitError(graph.ErrCircularDependency.Error(), 1)
+		for _, cycle := range err.(*graph.CircularDependencyError).Cycles {
+			for i, name := range cycle {
+				next := cycle[(i+1)%len(cycle)]
+				from, ok := circular.GetNode(name)
+				if !ok {
+					from = graph.NewNode(name)
+					circular.AddNode(from)
+				}
+				to, ok := circular.GetNode(next)
+				if !ok {
+					to = graph.NewNode(next)
+					circular.AddNode(to)
+				}
+				circular.AddEdge(from, to)
+			}
+		}
+		if werr := circular.Write("circular", format, os.Stdout); werr != nil {
+			return cli.NewExitError(werr.Error(), 1)
+		}
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	name := c.String("graph")
+	switch name {
+	case "resources":
+	case "reversed":
+		g = g.Reversed()
+	case "reduced":
+		g, err = g.TransitiveReduction()
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+	default:
+		return cli.NewExitError(fmt.Sprintf("Unknown graph %q", name), 64)
+	}
+
+	if err := g.Write(name, format, os.Stdout); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+// showImpact displays the resources and triggers, which are affected
+// by a change of a resource. The impact is displayed as text if no
+// format is given and as a graph of the affected resources otherwise.
+func showImpact(collection resource.Collection, id, format string) error {
+	impact, err := collection.Impact(id)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	switch format {
+	case "":
+	case graph.FormatJSON:
+		data, err := json.MarshalIndent(impact, "", "  ")
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		fmt.Println(string(data))
+		return nil
+	default:
+		g, err := impactGraph(collection, impact)
+		if err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		if err := g.Write("impact", format, os.Stdout); err != nil {
+			return cli.NewExitError(err.Error(), 1)
+		}
+		return nil
+	}
+
+	fmt.Printf("Resources affected by %s:\n", id)
+	for _, dependent := range impact.Dependents {
+		fmt.Printf("    %s\n", dependent)
+	}
+
+	fmt.Printf("Triggers which could fire:\n")
+	for _, t := range impact.Triggers {
+		fmt.Printf("    %s: %s -> %s\n", t.Kind, t.Source, t.Resource)
 	}
 
+	fmt.Printf("%d resource(s) and %d trigger(s) affected by %s\n", len(impact.Dependents), len(impact.Triggers), id)
+
 	return nil
 }
+
+// impactGraph creates a graph of the resources affected by a change
+// of a resource. Resources are connected to the resources they depend
+// on or react to and are annotated with the triggers they own.
+func impactGraph(collection resource.Collection, impact *resource.Impact) (*graph.Graph, error) {
+	full, err := collection.DependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	g := graph.New()
+	for _, id := range append([]string{impact.ID}, impact.Dependents...) {
+		g.AddNode(graph.NewNode(id))
+	}
+
+	// Edges between the nodes, which are already connected
+	connected := make(map[[2]string]bool)
+	connect := func(from, to string) {
+		src, ok := g.GetNode(from)
+		if !ok {
+			return
+		}
+		dst, ok := g.GetNode(to)
+		if !ok || connected[[2]string{from, to}] {
+			return
+		}
+		connected[[2]string{from, to}] = true
+		g.AddEdge(src, dst)
+	}
+
+	for name := range g.Nodes {
+		node, ok := full.GetNode(name)
+		if !ok {
+			continue
+		}
+		for _, edge := range node.Edges {
+			connect(name, edge.Name)
+		}
+	}
+
+	triggers := make(map[string][]string)
+	for _, t := range impact.Triggers {
+		connect(t.Resource, t.Source)
+		triggers[t.Resource] = append(triggers[t.Resource], fmt.Sprintf("%s: %s", t.Kind, t.Source))
+	}
+
+	for name, list := range triggers {
+		if node, ok := g.GetNode(name); ok {
+			node.Attributes["triggers"] = strings.Join(list, ", ")
+		}
+	}
+
+	return g, nil
+}
+
+// isValidFormat checks if the graph output format is supported
+func isValidFormat(format string) bool {
+	for _, f := range graph.Formats {
+		if f == format {
+			return true
+		}
+	}
+
+	return false
+}
+
+// annotate adds the resource type, concurrency flag and
+// subscriptions of the resources to the graph nodes
+func annotate(g *graph.Graph, collection resource.Collection) {
+	for id, r := range collection {
+		node, ok := g.GetNode(id)
+		if !ok {
+			continue
+		}
+
+		node.Attributes["type"] = strings.SplitN(id, "[", 2)[0]
+		node.Attributes["concurrent"] = strconv.FormatBool(r.IsConcurrent())
+
+		var subscriptions []string
+		for dep := range r.SubscribedTo() {
+			subscriptions = append(subscriptions, dep)
+		}
+		if len(subscriptions) > 0 {
+			sort.Strings(subscriptions)
+			node.Attributes["subscribes"] = strings.Join(subscriptions, ", ")
+		}
+	}
+}
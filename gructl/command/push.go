@@ -55,6 +55,21 @@ func NewPushCommand() cli.Command {
 				Value: "",
 				Usage: "match minions with given classifier pattern",
 			},
+			cli.StringFlag{
+				Name:  "tags",
+				Value: "",
+				Usage: "comma-separated list of tags to process",
+			},
+			cli.StringFlag{
+				Name:  "skip-tags",
+				Value: "",
+				Usage: "comma-separated list of tags to skip",
+			},
+			cli.StringFlag{
+				Name:  "only",
+				Value: "",
+				Usage: "comma-separated list of resource ids to process",
+			},
 		},
 	}
 
@@ -72,6 +87,9 @@ func execPushCommand(c *cli.Context) error {
 	// loaded and processed by the remote minions
 	main := c.Args()[0]
 	t := task.New(main, c.String("environment"))
+	t.Tags = parseList(c.String("tags"))
+	t.SkipTags = parseList(c.String("skip-tags"))
+	t.Only = parseList(c.String("only"))
 
 	client := newEtcdMinionClientFromFlags(c)
 
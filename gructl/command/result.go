@@ -50,6 +50,10 @@ func NewResultCommand() cli.Command {
 				Name:  "details",
 				Usage: "provide more details about the tasks",
 			},
+			cli.BoolFlag{
+				Name:  "report",
+				Usage: "display the JSON report of the tasks",
+			},
 		},
 	}
 
@@ -119,6 +123,11 @@ func execResultCommand(c *cli.Context) error {
 			return cli.NewExitError(err.Error(), 1)
 		}
 
+		if c.Bool("report") {
+			fmt.Printf("%s\n", t.Report)
+			continue
+		}
+
 		if c.Bool("details") {
 			table.AddRow("Minion:", minionID)
 			table.AddRow("Task ID:", t.ID)
@@ -131,7 +140,9 @@ func execResultCommand(c *cli.Context) error {
 		}
 	}
 
-	fmt.Println(table)
+	if !c.Bool("report") {
+		fmt.Println(table)
+	}
 
 	return nil
 }
@@ -30,7 +30,9 @@ import (
 	"os/signal"
 	"runtime"
 	"syscall"
+	"time"
 
+	"github.com/dnaeon/gru/catalog"
 	"github.com/dnaeon/gru/minion"
 	"github.com/urfave/cli"
 )
@@ -48,6 +50,16 @@ func NewServeCommand() cli.Command {
 				Usage: "number of goroutines used for concurrent processing",
 				Value: runtime.NumCPU(),
 			},
+			cli.DurationFlag{
+				Name:  "task-timeout",
+				Usage: "maximum time processing of a task may take",
+				Value: time.Duration(0),
+			},
+			cli.StringFlag{
+				Name:  "failure-policy",
+				Value: catalog.FailurePolicyContinue,
+				Usage: "how to proceed on failures: continue, fail-fast or fail-subtree",
+			},
 			cli.StringFlag{
 				Name:  "name",
 				Usage: "set minion name",
@@ -88,10 +100,12 @@ func execServeCommand(c *cli.Context) error {
 
 	etcdCfg := etcdConfigFromFlags(c)
 	minionCfg := &minion.EtcdMinionConfig{
-		Concurrency: concurrency,
-		Name:        name,
-		SiteRepo:    c.String("siterepo"),
-		EtcdConfig:  etcdCfg,
+		Concurrency:   concurrency,
+		TaskTimeout:   c.Duration("task-timeout"),
+		FailurePolicy: c.String("failure-policy"),
+		Name:          name,
+		SiteRepo:      c.String("siterepo"),
+		EtcdConfig:    etcdCfg,
 	}
 
 	m, err := minion.NewEtcdMinion(minionCfg)
@@ -63,6 +63,19 @@ func newEtcdMinionClientFromFlags(c *cli.Context) client.Client {
 	return klient
 }
 
+// Parses a comma-separated list of values.
+// Empty values are ignored.
+func parseList(s string) []string {
+	var result []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
 // Parses a classifier pattern and returns
 // minions which match the given classifier pattern.
 // A classifier pattern is described as 'key=regexp',
@@ -81,6 +81,8 @@ func Main() {
 		command.NewLastseenCommand(),
 		command.NewResultCommand(),
 		command.NewGraphCommand(),
+		command.NewCompileCommand(),
+		command.NewDiffCommand(),
 	}
 
 	app.Run(os.Args)
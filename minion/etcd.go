@@ -88,6 +88,10 @@ type etcdMinion struct {
 	// The Git repository of the site repo
 	gitRepo *utils.GitRepo
 
+	// Path to the local state file, which is
+	// located next to the site repo
+	stateFile string
+
 	// Channel used to signal for shutdown time
 	done chan struct{}
 }
@@ -98,6 +102,14 @@ type EtcdMinionConfig struct {
 	// Number of goroutines used for concurrent resource processing
 	Concurrency int
 
+	// TaskTimeout is the maximum amount of time processing
+	// of a task may take. Zero means no timeout.
+	TaskTimeout time.Duration
+
+	// FailurePolicy specifies how to proceed when processing
+	// of a resource from a task fails
+	FailurePolicy string
+
 	// Name of the minion
 	Name string
 
@@ -138,6 +150,7 @@ func NewEtcdMinion(config *EtcdMinionConfig) (Minion, error) {
 		kapi:          etcdclient.NewKeysAPI(c),
 		taskQueue:     make(chan *task.Task),
 		gitRepo:       gitRepo,
+		stateFile:     filepath.Join(cwd, "state.json"),
 		done:          make(chan struct{}),
 	}
 
@@ -239,12 +252,18 @@ func (m *etcdMinion) processTask(t *task.Task) error {
 	defer L.Close()
 
 	config := &catalog.Config{
-		Module:      t.Command,
-		DryRun:      t.DryRun,
-		Logger:      log.New(&buf, "", log.LstdFlags),
-		SiteRepo:    m.gitRepo.Path,
-		L:           L,
-		Concurrency: m.config.Concurrency,
+		Module:        t.Command,
+		DryRun:        t.DryRun,
+		Logger:        log.New(&buf, "", log.LstdFlags),
+		SiteRepo:      m.gitRepo.Path,
+		StateFile:     m.stateFile,
+		L:             L,
+		Concurrency:   m.config.Concurrency,
+		Timeout:       m.config.TaskTimeout,
+		Tags:          t.Tags,
+		SkipTags:      t.SkipTags,
+		Only:          t.Only,
+		FailurePolicy: m.config.FailurePolicy,
 	}
 
 	katalog := catalog.New(config)
@@ -257,6 +276,12 @@ func (m *etcdMinion) processTask(t *task.Task) error {
 	status := katalog.Run()
 	status.Summary(config.Logger)
 
+	report, err := json.Marshal(status.Report())
+	if err != nil {
+		log.Printf("Unable to create task report: %s\n", err)
+	}
+
+	t.Report = report
 	t.Result = buf.String()
 	t.State = task.TaskStateSuccess
 
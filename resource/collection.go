@@ -73,15 +73,57 @@ func (c Collection) DependencyGraph() (*graph.Graph, error) {
 			g.AddEdge(nodes[id], nodes[dep])
 		}
 
+		// Create edges between the resources which depend on
+		// the current one and the current resource
+		for _, dependent := range r.Dependents() {
+			if _, ok := c[dependent]; !ok {
+				return g, fmt.Errorf("%s must be processed before %s, which does not exist", id, dependent)
+			}
+			g.AddEdge(nodes[dependent], nodes[id])
+		}
+
 		// Create edges between the nodes and the resources for
-		// which we subscribe for changes to
-		for dep := range r.SubscribedTo() {
-			if _, ok := c[dep]; !ok {
-				return g, fmt.Errorf("%s subscribes to %s, which does not exist", id, dep)
+		// which we subscribe for changes, failures, etc.
+		triggers := []TriggerMap{
+			r.SubscribedTo(),
+			r.FailureTriggers(),
+			r.SuccessTriggers(),
+			r.UnchangedTriggers(),
+		}
+
+		for _, t := range triggers {
+			for dep := range t {
+				if _, ok := c[dep]; !ok {
+					return g, fmt.Errorf("%s subscribes to %s, which does not exist", id, dep)
+				}
+				g.AddEdge(nodes[id], nodes[dep])
 			}
-			g.AddEdge(nodes[id], nodes[dep])
+		}
+
+		// Create edges between the notified resources and the
+		// current one, so that the current resource is processed first
+		for _, notified := range r.Notifies() {
+			if _, ok := c[notified]; !ok {
+				return g, fmt.Errorf("%s notifies %s, which does not exist", id, notified)
+			}
+			g.AddEdge(nodes[notified], nodes[id])
 		}
 	}
 
 	return g, nil
 }
+
+// Dependencies returns a map which keys are the resource ids and
+// their values are the ids of the resources they depend on, including
+// dependencies declared by the dependent resources themselves.
+func (c Collection) Dependencies() map[string][]string {
+	deps := make(map[string][]string)
+	for id, r := range c {
+		deps[id] = append(deps[id], r.Dependencies()...)
+		for _, dependent := range r.Dependents() {
+			deps[dependent] = append(deps[dependent], id)
+		}
+	}
+
+	return deps
+}
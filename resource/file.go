@@ -84,6 +84,17 @@ func (bf *BaseFile) setMode() error {
 	return dst.Chmod(bf.Mode)
 }
 
+// modeValues returns the current and desired permissions of the file.
+func (bf *BaseFile) modeValues() (interface{}, interface{}, error) {
+	dst := utils.NewFileUtil(bf.Path)
+	mode, err := dst.Mode()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fmt.Sprintf("%#o", mode.Perm()), fmt.Sprintf("%#o", bf.Mode), nil
+}
+
 // isOwnerSynced checks whether the file ownership is correct.
 func (bf *BaseFile) isOwnerSynced() (bool, error) {
 	dst := utils.NewFileUtil(bf.Path)
@@ -109,6 +120,20 @@ func (bf *BaseFile) setOwner() error {
 	return dst.SetOwner(bf.Owner, bf.Group)
 }
 
+// ownerValues returns the current and desired ownership of the file.
+func (bf *BaseFile) ownerValues() (interface{}, interface{}, error) {
+	dst := utils.NewFileUtil(bf.Path)
+	owner, err := dst.Owner()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := fmt.Sprintf("%s:%s", owner.User.Username, owner.Group.Name)
+	want := fmt.Sprintf("%s:%s", bf.Owner, bf.Group)
+
+	return current, want, nil
+}
+
 // File resource manages files.
 //
 // Example:
@@ -151,6 +176,20 @@ func (f *File) isContentSynced() (bool, error) {
 	return srcMd5 == dstMd5, nil
 }
 
+// contentValues returns the md5 checksums of the current
+// and desired content of the file.
+func (f *File) contentValues() (interface{}, interface{}, error) {
+	dst := utils.NewFileUtil(f.Path)
+	dstMd5, err := dst.Md5()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srcMd5 := fmt.Sprintf("%x", md5.Sum(f.Content))
+
+	return dstMd5, srcMd5, nil
+}
+
 // setContent sets the content of the file.
 func (f *File) setContent() error {
 	dst := utils.NewFileUtil(f.Path)
@@ -189,6 +228,9 @@ func NewFile(name string) (Resource, error) {
 				AbsentStatesList:  []string{"absent"},
 				Concurrent:        true,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
 			Path:  name,
 			Mode:  0644,
@@ -205,16 +247,19 @@ func NewFile(name string) (Resource, error) {
 			PropertyName:         "mode",
 			PropertySetFunc:      f.setMode,
 			PropertyIsSyncedFunc: f.isModeSynced,
+			PropertyValuesFunc:   f.modeValues,
 		},
 		&ResourceProperty{
 			PropertyName:         "ownership",
 			PropertySetFunc:      f.setOwner,
 			PropertyIsSyncedFunc: f.isOwnerSynced,
+			PropertyValuesFunc:   f.ownerValues,
 		},
 		&ResourceProperty{
 			PropertyName:         "content",
 			PropertySetFunc:      f.setContent,
 			PropertyIsSyncedFunc: f.isContentSynced,
+			PropertyValuesFunc:   f.contentValues,
 		},
 	}
 
@@ -236,17 +281,25 @@ func (f *File) Validate() error {
 
 // Initialize initializes the file resource.
 func (f *File) Initialize() error {
-	// Set file content from the given source file if any.
+	return f.Resolve()
+}
+
+// Resolve sets the file content from the given source file if any.
+// Once resolved the file no longer refers to the source file.
+func (f *File) Resolve() error {
 	// TODO: Currently this works only for files in the site repo.
 	// TODO: Implement a generic file content fetcher.
-	if f.Source != "" {
-		src := filepath.Join(DefaultConfig.SiteRepo, f.Source)
-		content, err := ioutil.ReadFile(src)
-		if err != nil {
-			return err
-		}
-		f.Content = content
+	if f.Source == "" {
+		return nil
+	}
+
+	src := filepath.Join(DefaultConfig.SiteRepo, f.Source)
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
 	}
+	f.Content = content
+	f.Source = ""
 
 	return nil
 }
@@ -328,6 +381,9 @@ func NewDirectory(name string) (Resource, error) {
 				AbsentStatesList:  []string{"absent"},
 				Concurrent:        true,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
 			Path:  name,
 			Mode:  0755,
@@ -343,11 +399,13 @@ func NewDirectory(name string) (Resource, error) {
 			PropertyName:         "mode",
 			PropertySetFunc:      d.setMode,
 			PropertyIsSyncedFunc: d.isModeSynced,
+			PropertyValuesFunc:   d.modeValues,
 		},
 		&ResourceProperty{
 			PropertyName:         "ownership",
 			PropertySetFunc:      d.setOwner,
 			PropertyIsSyncedFunc: d.isOwnerSynced,
+			PropertyValuesFunc:   d.ownerValues,
 		},
 	}
 
@@ -428,6 +486,9 @@ func NewLink(name string) (Resource, error) {
 				AbsentStatesList:  []string{"absent"},
 				Concurrent:        true,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
 			Path: name,
 		},
@@ -440,6 +501,10 @@ func NewLink(name string) (Resource, error) {
 
 // Validate validates the link resource.
 func (l *Link) Validate() error {
+	if l.wantAbsent() {
+		return nil
+	}
+
 	if l.Source == "" {
 		return errors.New("must provide source file")
 	}
@@ -459,7 +524,8 @@ func (l *Link) Evaluate() (State, error) {
 		Want:    l.State,
 	}
 
-	_, err := os.Stat(l.Path)
+	// Links to removed source files are still present
+	_, err := os.Lstat(l.Path)
 	if os.IsNotExist(err) {
 		state.Current = "absent"
 		return state, nil
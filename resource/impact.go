@@ -0,0 +1,148 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package resource
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Trigger kinds reported by the impact analysis
+const (
+	// TriggerSubscribe is used for subscriptions to changes
+	TriggerSubscribe = "subscribe"
+
+	// TriggerOnFailure is used for failure triggers
+	TriggerOnFailure = "on_failure"
+
+	// TriggerOnSuccess is used for success triggers
+	TriggerOnSuccess = "on_success"
+
+	// TriggerOnUnchanged is used for triggers, which are
+	// executed when a resource has not changed
+	TriggerOnUnchanged = "on_unchanged"
+
+	// TriggerNotify is used for resources, which are
+	// refreshed when another resource has changed
+	TriggerNotify = "notify"
+)
+
+// Trigger describes a trigger, which could fire as a
+// result of processing a resource
+type Trigger struct {
+	// Resource is the id of the resource, which owns the trigger
+	// or is refreshed when notified
+	Resource string `json:"resource"`
+
+	// Source is the id of the resource, which fires the trigger
+	Source string `json:"source"`
+
+	// Kind is the kind of the trigger
+	Kind string `json:"kind"`
+}
+
+// Impact contains the result of the impact analysis for a resource
+type Impact struct {
+	// ID is the id of the analyzed resource
+	ID string `json:"id"`
+
+	// Dependents contains the ids of the resources, which
+	// transitively depend on, subscribe to or are notified
+	// by the analyzed resource
+	Dependents []string `json:"dependents"`
+
+	// Triggers contains the triggers, which could fire when
+	// the analyzed resource or any of it's dependents is processed
+	Triggers []Trigger `json:"triggers"`
+}
+
+// Impact returns the resources and triggers, which are affected
+// by a change of the resource with the given id.
+func (c Collection) Impact(id string) (*Impact, error) {
+	if _, ok := c[id]; !ok {
+		return nil, fmt.Errorf("%s does not exist", id)
+	}
+
+	g, err := c.DependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	node, _ := g.GetNode(id)
+	impact := &Impact{
+		ID:         id,
+		Dependents: make([]string, 0),
+		Triggers:   make([]Trigger, 0),
+	}
+
+	affected := map[string]bool{id: true}
+	for _, n := range g.Ancestors(node) {
+		affected[n.Name] = true
+		impact.Dependents = append(impact.Dependents, n.Name)
+	}
+
+	for rid, r := range c {
+		triggers := map[string]TriggerMap{
+			TriggerSubscribe:   r.SubscribedTo(),
+			TriggerOnFailure:   r.FailureTriggers(),
+			TriggerOnSuccess:   r.SuccessTriggers(),
+			TriggerOnUnchanged: r.UnchangedTriggers(),
+		}
+
+		for kind, t := range triggers {
+			for source := range t {
+				if affected[source] {
+					impact.Triggers = append(impact.Triggers, Trigger{Resource: rid, Source: source, Kind: kind})
+				}
+			}
+		}
+
+		if affected[rid] {
+			for _, notified := range r.Notifies() {
+				impact.Triggers = append(impact.Triggers, Trigger{Resource: notified, Source: rid, Kind: TriggerNotify})
+			}
+		}
+	}
+	sort.Sort(byTrigger(impact.Triggers))
+
+	return impact, nil
+}
+
+// byTrigger sorts triggers by resource, source and kind
+type byTrigger []Trigger
+
+func (t byTrigger) Len() int      { return len(t) }
+func (t byTrigger) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t byTrigger) Less(i, j int) bool {
+	if t[i].Resource != t[j].Resource {
+		return t[i].Resource < t[j].Resource
+	}
+	if t[i].Source != t[j].Source {
+		return t[i].Source < t[j].Source
+	}
+
+	return t[i].Kind < t[j].Kind
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package resource
+
+import "testing"
+
+func TestCollectionImpact(t *testing.T) {
+	L := newLuaState()
+	defer L.Close()
+
+	const code = `
+	pkg = resource.shell.new("install snmpd")
+
+	conf = resource.file.new("/etc/snmp/snmpd.conf")
+	conf.require = { pkg:ID() }
+	conf.notify = { "shell[reload snmpd]" }
+
+	svc = resource.shell.new("restart snmpd")
+	svc.require = { conf:ID() }
+
+	reload = resource.shell.new("reload snmpd")
+
+	hook = resource.shell.new("hook")
+	hook.subscribe[svc:ID()] = function() end
+	hook.on_failure[pkg:ID()] = function() end
+	`
+
+	if err := L.DoString(code); err != nil {
+		t.Fatal(err)
+	}
+
+	var resources []Resource
+	for _, name := range []string{"pkg", "conf", "svc", "reload", "hook"} {
+		resources = append(resources, luaResource(L, name).(Resource))
+	}
+
+	collection, err := CreateCollection(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	impact, err := collection.Impact("file[/etc/snmp/snmpd.conf]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantDependents := []string{"shell[hook]", "shell[reload snmpd]", "shell[restart snmpd]"}
+	errorIfNotEqual(t, wantDependents, impact.Dependents)
+
+	wantTriggers := []Trigger{
+		{Resource: "shell[hook]", Source: "shell[restart snmpd]", Kind: TriggerSubscribe},
+		{Resource: "shell[reload snmpd]", Source: "file[/etc/snmp/snmpd.conf]", Kind: TriggerNotify},
+	}
+	errorIfNotEqual(t, wantTriggers, impact.Triggers)
+
+	if _, err := collection.Impact("shell[unknown]"); err == nil {
+		t.Error("want an error for unknown resource, got nil")
+	}
+}
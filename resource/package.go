@@ -28,9 +28,16 @@
 package resource
 
 import (
+	"bufio"
+	"context"
 	"errors"
+	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"strings"
+	"syscall"
+	"unicode"
 
 	"github.com/dnaeon/gru/utils"
 )
@@ -40,6 +47,11 @@ var ErrNoPackageProviderFound = errors.New("No suitable package provider found")
 
 // BasePackage is the base resource type for package management
 // It's purpose is to be embedded into other package resource providers.
+//
+// Packages can be pinned to a specific version by setting the
+// version of the package, which downgrades the package if a newer
+// version is installed. When the state of the package is set
+// to "latest" the package is upgraded if a newer version is available.
 type BasePackage struct {
 	Base
 
@@ -49,12 +61,64 @@ type BasePackage struct {
 	// Version of the package.
 	Version string `luar:"version"`
 
+	// Type the provider of the package resource is registered for,
+	// which is used for rebuilding the resource with the same
+	// package manager, e.g. when purging it
+	provider string `luar:"-"`
+
 	// Package manager to use
 	manager string `luar:"-"`
 
+	// Command to use when querying a package.
+	// Defaults to the package manager if not set.
+	query string `luar:"-"`
+
 	// Arguments to use when quering a package
 	queryArgs []string `luar:"-"`
 
+	// Function used to parse the output of the query command.
+	// Returns the installed version of the package and a boolean
+	// indicating whether the package is installed.
+	parseQuery func(name string, out []byte) (string, bool) `luar:"-"`
+
+	// Command to use when querying the latest available version
+	// of a package. Defaults to the package manager if not set.
+	latest string `luar:"-"`
+
+	// Arguments to use when querying the latest available version
+	latestArgs []string `luar:"-"`
+
+	// Function used to parse the output of the latest version query.
+	// Returns an empty string if no newer version is available.
+	parseLatest func(name string, out []byte) string `luar:"-"`
+
+	// Exit status of the latest version query command, when no
+	// newer version of the package is available, e.g. yum exits
+	// with status 1 if there are no matching updates. Any other
+	// failure is reported as an error. Zero if the command
+	// succeeds in that case.
+	latestNoneStatus int `luar:"-"`
+
+	// Function used to create the name of a package with a specific
+	// version using the syntax of the package manager. If not set the
+	// package manager does not support installing specific versions.
+	versionSpec func(name, version string) string `luar:"-"`
+
+	// Arguments to use when listing all installed packages
+	// using the query command
+	inventoryArgs []string `luar:"-"`
+
+	// Function used to parse the output of the inventory query.
+	// Returns the installed packages and their versions.
+	parseInventory func(out []byte) map[string]string `luar:"-"`
+
+	// Installed packages loaded by a batch, which are
+	// used instead of querying the package manager
+	inventory map[string]string `luar:"-"`
+
+	// Additional environment variables for the package manager
+	env []string `luar:"-"`
+
 	// Arguments to use when installing a package
 	installArgs []string `luar:"-"`
 
@@ -63,91 +127,635 @@ type BasePackage struct {
 
 	// Arguments to use when updating a package
 	updateArgs []string `luar:"-"`
+
+	// Arguments to use when installing an older version of a
+	// package than the installed one. If not set the install
+	// arguments are used.
+	downgradeArgs []string `luar:"-"`
+}
+
+// queryNotInstalledStatus is the exit status of the query
+// command of the package managers, when the queried package
+// is not installed. Any other failure is reported as an error.
+const queryNotInstalledStatus = 1
+
+// Validate validates the package resource
+func (bp *BasePackage) Validate() error {
+	if err := bp.Base.Validate(); err != nil {
+		return err
+	}
+
+	if bp.Version != "" && bp.versionSpec == nil {
+		return fmt.Errorf("%s package manager does not support installing specific versions", bp.ID())
+	}
+
+	if bp.Version != "" && bp.State == "latest" {
+		return fmt.Errorf("%s version cannot be used with the latest state", bp.ID())
+	}
+
+	return nil
 }
 
 // Evaluate evaluates the state of the package
 func (bp *BasePackage) Evaluate() (State, error) {
+	return bp.EvaluateContext(context.Background())
+}
+
+// EvaluateContext evaluates the state of the package using the given context
+func (bp *BasePackage) EvaluateContext(ctx context.Context) (State, error) {
 	s := State{
 		Current: "unknown",
 		Want:    bp.State,
 	}
 
-	_, err := exec.LookPath(bp.manager)
+	_, installed, err := bp.installedVersion(ctx)
 	if err != nil {
 		return s, err
 	}
 
-	bp.queryArgs = append(bp.queryArgs, bp.Package)
-	cmd := exec.Command(bp.manager, bp.queryArgs...)
-	err = cmd.Run()
-
-	if err != nil {
-		s.Current = "deinstalled"
-	} else {
+	if installed {
 		s.Current = "installed"
+	} else {
+		s.Current = "deinstalled"
 	}
 
 	return s, nil
 }
 
-// Create installs the package
-func (bp *BasePackage) Create() error {
-	Logf("%s installing package\n", bp.ID())
+// installedVersion returns the installed version of the package
+// and a boolean indicating whether the package is installed.
+func (bp *BasePackage) installedVersion(ctx context.Context) (string, bool, error) {
+	if bp.inventory != nil {
+		version, ok := bp.inventory[bp.Package]
+		return version, ok, nil
+	}
+
+	query := bp.queryCommand()
+	if _, err := exec.LookPath(query); err != nil {
+		return "", false, err
+	}
+
+	args := append([]string{}, bp.queryArgs...)
+	args = append(args, bp.Package)
+	out, err := exec.CommandContext(ctx, query, args...).Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", false, ctx.Err()
+		}
+
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return "", false, err
+		}
+
+		status, ok := exitErr.Sys().(syscall.WaitStatus)
+		if ok && status.ExitStatus() == queryNotInstalledStatus {
+			return "", false, nil
+		}
+
+		return "", false, fmt.Errorf("unable to query package %s: %s: %s", bp.Package, err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	version, installed := bp.parseQuery(bp.Package, out)
+
+	return version, installed, nil
+}
+
+// Provider returns the type the provider of
+// the package resource is registered for.
+func (bp *BasePackage) Provider() string {
+	return bp.provider
+}
+
+// queryCommand returns the command used for querying packages
+func (bp *BasePackage) queryCommand() string {
+	if bp.query == "" {
+		return bp.manager
+	}
+
+	return bp.query
+}
+
+// latestVersion returns the latest version of the package, which
+// is available for installation. An empty string is returned if
+// no newer version is available.
+func (bp *BasePackage) latestVersion(ctx context.Context) (string, error) {
+	latest := bp.latest
+	if latest == "" {
+		latest = bp.manager
+	}
+
+	args := append([]string{}, bp.latestArgs...)
+	args = append(args, bp.Package)
+	out, err := exec.CommandContext(ctx, latest, args...).Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return "", err
+		}
+
+		status, ok := exitErr.Sys().(syscall.WaitStatus)
+		if ok && bp.latestNoneStatus != 0 && status.ExitStatus() == bp.latestNoneStatus {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("unable to query latest version of package %s: %s: %s", bp.Package, err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	return bp.parseLatest(bp.Package, out), nil
+}
 
-	bp.installArgs = append(bp.installArgs, bp.Package)
-	cmd := exec.Command(bp.manager, bp.installArgs...)
+// run executes the package manager with the given arguments
+// and logs it's output.
+func (bp *BasePackage) run(ctx context.Context, args ...string) error {
+	return bp.runAs(ctx, bp.ID(), args...)
+}
+
+// runAs executes the package manager with the given arguments
+// and logs it's output using the given prefix.
+func (bp *BasePackage) runAs(ctx context.Context, prefix string, args ...string) error {
+	cmd := exec.CommandContext(ctx, bp.manager, args...)
+	cmd.Env = append(os.Environ(), bp.env...)
 	out, err := cmd.CombinedOutput()
 
 	for _, line := range strings.Split(string(out), "\n") {
-		Logf("%s %s\n", bp.ID(), line)
+		Logf("%s %s\n", prefix, line)
 	}
 
 	return err
 }
 
+// target returns the package name, which is passed to the package
+// manager when installing the package.
+func (bp *BasePackage) target() string {
+	if bp.Version == "" {
+		return bp.Package
+	}
+
+	return bp.versionSpec(bp.Package, bp.Version)
+}
+
+// Create installs the package
+func (bp *BasePackage) Create() error {
+	return bp.CreateContext(context.Background())
+}
+
+// CreateContext installs the package using the given context
+func (bp *BasePackage) CreateContext(ctx context.Context) error {
+	Logf("%s installing package\n", bp.ID())
+
+	args := append([]string{}, bp.installArgs...)
+
+	return bp.run(ctx, append(args, bp.target())...)
+}
+
 // Delete deletes the package
 func (bp *BasePackage) Delete() error {
-	Logf("%s removing package\n", bp.ID())
+	return bp.DeleteContext(context.Background())
+}
 
-	bp.deinstallArgs = append(bp.deinstallArgs, bp.Package)
-	cmd := exec.Command(bp.manager, bp.deinstallArgs...)
-	out, err := cmd.CombinedOutput()
+// DeleteContext deletes the package using the given context
+func (bp *BasePackage) DeleteContext(ctx context.Context) error {
+	Logf("%s removing package\n", bp.ID())
 
-	for _, line := range strings.Split(string(out), "\n") {
-		Logf("%s %s\n", bp.ID(), line)
-	}
+	args := append([]string{}, bp.deinstallArgs...)
 
-	return err
+	return bp.run(ctx, append(args, bp.Package)...)
 }
 
 // Update updates the package
 func (bp *BasePackage) Update() error {
+	return bp.UpdateContext(context.Background())
+}
+
+// UpdateContext updates the package using the given context
+func (bp *BasePackage) UpdateContext(ctx context.Context) error {
 	Logf("%s updating package\n", bp.ID())
 
-	bp.updateArgs = append(bp.updateArgs, bp.Package)
-	cmd := exec.Command(bp.manager, bp.updateArgs...)
-	out, err := cmd.CombinedOutput()
+	args := append([]string{}, bp.updateArgs...)
+
+	return bp.run(ctx, append(args, bp.Package)...)
+}
+
+// basePackage returns the base package resource
+func (bp *BasePackage) basePackage() *BasePackage {
+	return bp
+}
+
+// BatchKey returns the key used for grouping package resources,
+// which are managed by the same package manager.
+func (bp *BasePackage) BatchKey() string {
+	return "package:" + bp.manager
+}
+
+// NewBatch creates a batch for processing the given package
+// resources using a single package manager transaction.
+func (bp *BasePackage) NewBatch(resources []Resource) Batch {
+	b := &packageBatch{
+		manager:  bp,
+		packages: make([]*BasePackage, 0, len(resources)),
+	}
+
+	for _, r := range resources {
+		if p, ok := r.(packageResource); ok {
+			b.packages = append(b.packages, p.basePackage())
+		}
+	}
 
+	return b
+}
+
+// packageResource is implemented by resources embedding BasePackage
+type packageResource interface {
+	basePackage() *BasePackage
+}
+
+// packageBatch type processes packages, which are managed by
+// the same package manager in a single transaction.
+type packageBatch struct {
+	// Package used for running the package manager
+	manager *BasePackage
+
+	// Packages in the batch
+	packages []*BasePackage
+}
+
+// Load queries the package manager for the installed packages
+// and their versions, which is then used when evaluating the
+// packages from the batch.
+func (b *packageBatch) Load(ctx context.Context) error {
+	query := b.manager.queryCommand()
+	if _, err := exec.LookPath(query); err != nil {
+		return err
+	}
+
+	out, err := exec.CommandContext(ctx, query, b.manager.inventoryArgs...).Output()
+	if err != nil {
+		return err
+	}
+
+	inventory := b.manager.parseInventory(out)
+	for _, p := range b.packages {
+		p.inventory = inventory
+	}
+
+	return nil
+}
+
+// Create installs the given packages in a single transaction
+func (b *packageBatch) Create(ctx context.Context, resources []Resource) error {
+	args := append([]string{}, b.manager.installArgs...)
+	for _, r := range resources {
+		p := r.(packageResource).basePackage()
+		Logf("%s installing package\n", p.ID())
+		args = append(args, p.target())
+	}
+
+	return b.manager.runAs(ctx, b.manager.manager, args...)
+}
+
+// Delete removes the given packages in a single transaction
+func (b *packageBatch) Delete(ctx context.Context, resources []Resource) error {
+	args := append([]string{}, b.manager.deinstallArgs...)
+	for _, r := range resources {
+		p := r.(packageResource).basePackage()
+		Logf("%s removing package\n", p.ID())
+		args = append(args, p.Package)
+	}
+
+	return b.manager.runAs(ctx, b.manager.manager, args...)
+}
+
+// Close discards the loaded packages, so that packages
+// are evaluated by querying the package manager again.
+func (b *packageBatch) Close() {
+	for _, p := range b.packages {
+		p.inventory = nil
+	}
+}
+
+// parseNameVersion parses inventory output, which contains
+// the name and version of a package on each line.
+func parseNameVersion(out []byte) map[string]string {
+	inventory := make(map[string]string)
 	for _, line := range strings.Split(string(out), "\n") {
-		Logf("%s %s\n", bp.ID(), line)
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Keep the first version of packages, which
+		// are installed multiple times, e.g. kernels
+		if _, ok := inventory[fields[0]]; !ok {
+			inventory[fields[0]] = fields[1]
+		}
 	}
 
-	return err
+	return inventory
+}
+
+// versionProperty returns the property used for managing
+// the version of the package.
+func (bp *BasePackage) versionProperty() Property {
+	p := &ResourceProperty{
+		PropertyName:                "version",
+		PropertySetFunc:             bp.setVersion,
+		PropertySetContextFunc:      bp.setVersionContext,
+		PropertyIsSyncedFunc:        bp.isVersionSynced,
+		PropertyIsSyncedContextFunc: bp.isVersionSyncedContext,
+		PropertyValuesFunc:          bp.versionValues,
+		PropertyValuesContextFunc:   bp.versionValuesContext,
+	}
+
+	return p
+}
+
+// wantVersion returns the installed version of the package and
+// the version, which should be installed. An empty wanted version
+// means that any version of the package is accepted.
+func (bp *BasePackage) wantVersion(ctx context.Context) (string, string, error) {
+	installed, ok, err := bp.installedVersion(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !ok {
+		return "", "", ErrResourceAbsent
+	}
+
+	if bp.State == "latest" {
+		latest, err := bp.latestVersion(ctx)
+		if err != nil {
+			return "", "", err
+		}
+
+		return installed, latest, nil
+	}
+
+	return installed, bp.Version, nil
+}
+
+// isVersionSynced checks whether the installed version
+// of the package is the wanted one.
+func (bp *BasePackage) isVersionSynced() (bool, error) {
+	return bp.isVersionSyncedContext(context.Background())
+}
+
+// isVersionSyncedContext checks whether the installed version
+// of the package is the wanted one using the given context.
+func (bp *BasePackage) isVersionSyncedContext(ctx context.Context) (bool, error) {
+	installed, want, err := bp.wantVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return want == "" || versionMatches(installed, want), nil
+}
+
+// versionValues returns the installed and wanted version of the package.
+func (bp *BasePackage) versionValues() (interface{}, interface{}, error) {
+	return bp.versionValuesContext(context.Background())
+}
+
+// versionValuesContext returns the installed and wanted version
+// of the package using the given context.
+func (bp *BasePackage) versionValuesContext(ctx context.Context) (interface{}, interface{}, error) {
+	installed, want, err := bp.wantVersion(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if want == "" {
+		want = installed
+	}
+
+	return installed, want, nil
+}
+
+// setVersion installs the wanted version of the package.
+func (bp *BasePackage) setVersion() error {
+	return bp.setVersionContext(context.Background())
+}
+
+// setVersionContext installs the wanted version of the package
+// using the given context. An error is returned if the package
+// manager did not install the wanted version.
+func (bp *BasePackage) setVersionContext(ctx context.Context) error {
+	if err := bp.installVersion(ctx); err != nil {
+		return err
+	}
+
+	// Packages loaded by a batch are no longer up-to-date
+	bp.inventory = nil
+
+	installed, want, err := bp.wantVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if want != "" && !versionMatches(installed, want) {
+		return fmt.Errorf("%s version %s is installed instead of %s", bp.ID(), installed, want)
+	}
+
+	return nil
+}
+
+// installVersion installs the wanted version of the package.
+// The package is downgraded if the installed version is newer
+// than the wanted one.
+func (bp *BasePackage) installVersion(ctx context.Context) error {
+	if bp.State == "latest" {
+		return bp.UpdateContext(ctx)
+	}
+
+	installed, want, err := bp.wantVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	if bp.downgradeArgs == nil || compareVersions(installed, want) <= 0 {
+		Logf("%s installing version %s\n", bp.ID(), want)
+
+		return bp.CreateContext(ctx)
+	}
+
+	Logf("%s downgrading from version %s to %s\n", bp.ID(), installed, want)
+
+	args := append([]string{}, bp.downgradeArgs...)
+
+	return bp.run(ctx, append(args, bp.target())...)
+}
+
+// versionMatches checks whether the installed version of a package
+// matches the wanted one. The wanted version matches if it is the
+// same as the installed one or if it is the installed version
+// without the release and epoch, e.g. "2.6" matches "1:2.6-3".
+func versionMatches(installed, want string) bool {
+	if !strings.Contains(want, ":") {
+		if i := strings.Index(installed, ":"); i != -1 {
+			installed = installed[i+1:]
+		}
+	}
+
+	return installed == want || strings.HasPrefix(installed, want+"-")
+}
+
+// compareVersions compares the installed version of a package with
+// the wanted one. It returns -1, 0 or 1 if the installed version is
+// older, the same or newer than the wanted one. The release and epoch
+// of the installed version are compared only if the wanted version has
+// them as well. Versions are compared segment by segment, where each
+// segment is either numeric or alphabetic, similar to rpmvercmp(3).
+func compareVersions(installed, want string) int {
+	if !strings.Contains(want, ":") {
+		if i := strings.Index(installed, ":"); i != -1 {
+			installed = installed[i+1:]
+		}
+	}
+
+	if !strings.Contains(want, "-") {
+		if i := strings.Index(installed, "-"); i != -1 {
+			installed = installed[:i]
+		}
+	}
+
+	a, b := versionSegments(installed), versionSegments(want)
+	for i := 0; i < len(a) && i < len(b); i++ {
+		x, y := a[i], b[i]
+
+		xNumeric := unicode.IsDigit(rune(x[0]))
+		yNumeric := unicode.IsDigit(rune(y[0]))
+		switch {
+		case xNumeric && !yNumeric:
+			return 1
+		case !xNumeric && yNumeric:
+			return -1
+		case xNumeric:
+			x, y = strings.TrimLeft(x, "0"), strings.TrimLeft(y, "0")
+			if len(x) != len(y) {
+				return compareInts(len(x), len(y))
+			}
+		}
+
+		if x != y {
+			return compareStrings(x, y)
+		}
+	}
+
+	return compareInts(len(a), len(b))
+}
+
+// versionSegments splits a version into it's numeric and alphabetic
+// segments, e.g. "2.6a-1" is split into "2", "6", "a" and "1".
+func versionSegments(version string) []string {
+	var segments []string
+	start := -1
+	for i, c := range version {
+		isSegment := unicode.IsDigit(c) || unicode.IsLetter(c)
+		if start != -1 && (!isSegment || unicode.IsDigit(c) != unicode.IsDigit(rune(version[start]))) {
+			segments = append(segments, version[start:i])
+			start = -1
+		}
+		if isSegment && start == -1 {
+			start = i
+		}
+	}
+
+	if start != -1 {
+		segments = append(segments, version[start:])
+	}
+
+	return segments
+}
+
+// compareInts returns -1, 0 or 1 if a is less, equal or greater than b
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// compareStrings returns -1, 0 or 1 if a is less, equal or greater than b
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// parseFirstLine returns the first line of the output of a query
+// command, which prints only the version of the package.
+func parseFirstLine(name string, out []byte) (string, bool) {
+	line := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+
+	return line, line != ""
+}
+
+// parseLatestFirstLine returns the first line of the output
+// of a latest version query command.
+func parseLatestFirstLine(name string, out []byte) string {
+	version, _ := parseFirstLine(name, out)
+
+	return version
+}
+
+// joinVersion creates the package name with a specific version
+// using the given separator, e.g. "tmux-2.6" or "tmux=2.6"
+func joinVersion(sep string) func(name, version string) string {
+	return func(name, version string) string {
+		return name + sep + version
+	}
 }
 
 // NewPackage creates a new resource for managing packages.
 // This provider tries to determine the most appropriate
 // package provider for you, so it is more like a meta-provider.
 //
+// The provider is determined from the os-release(5) file of the
+// system, and falls back to the release files used by older
+// GNU/Linux distros and the package managers found on BSD systems.
+//
 // Example:
 //   pkg = resource.package.new("tmux")
 //   pkg.state = "installed"
 func NewPackage(name string) (Resource, error) {
+	for _, path := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+
+		osRelease, err := parseOSRelease(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if provider, ok := packageProviderFor(osRelease); ok {
+			return provider(name)
+		}
+	}
+
 	// Releases files used by the various GNU/Linux distros
 	releases := map[string]Provider{
 		"/etc/arch-release":   NewPacman,
 		"/etc/centos-release": NewYum,
 		"/etc/redhat-release": NewYum,
+		"/etc/debian_version": NewApt,
+		"/etc/alpine-release": NewApk,
 		"/usr/local/sbin/pkg": NewPkgNG,
 	}
 
@@ -162,6 +770,75 @@ func NewPackage(name string) (Resource, error) {
 	return nil, ErrNoPackageProviderFound
 }
 
+// parseOSRelease parses the contents of an os-release(5) file
+// and returns the variables found in it.
+func parseOSRelease(r io.Reader) (map[string]string, error) {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		vars[parts[0]] = strings.Trim(parts[1], `"'`)
+	}
+
+	return vars, scanner.Err()
+}
+
+// packageProviderFor returns the package provider for the
+// operating system described by the os-release(5) variables.
+// The ID of the operating system is checked first, followed by
+// the operating systems listed in ID_LIKE.
+func packageProviderFor(osRelease map[string]string) (Provider, bool) {
+	providers := map[string]Provider{
+		"arch":      NewPacman,
+		"debian":    NewApt,
+		"ubuntu":    NewApt,
+		"fedora":    NewDnf,
+		"rhel":      newRedHatPackage,
+		"centos":    newRedHatPackage,
+		"alpine":    NewApk,
+		"opensuse":  NewZypper,
+		"suse":      NewZypper,
+		"sles":      NewZypper,
+		"freebsd":   NewPkgNG,
+		"dragonfly": NewPkgNG,
+	}
+
+	ids := []string{osRelease["ID"]}
+	ids = append(ids, strings.Fields(osRelease["ID_LIKE"])...)
+	for _, id := range ids {
+		// openSUSE uses ids such as opensuse-leap and opensuse-tumbleweed
+		if strings.HasPrefix(id, "opensuse") {
+			id = "opensuse"
+		}
+
+		if provider, ok := providers[id]; ok {
+			return provider, true
+		}
+	}
+
+	return nil, false
+}
+
+// newRedHatPackage creates a new resource for managing packages
+// on RHEL and CentOS systems. Dnf is used if it is available,
+// otherwise yum is used.
+func newRedHatPackage(name string) (Resource, error) {
+	if utils.NewFileUtil("/usr/bin/dnf").Exists() {
+		return NewDnf(name)
+	}
+
+	return NewYum(name)
+}
+
 // Pacman type represents the resource for package management on
 // Arch Linux systems.
 //
@@ -182,24 +859,46 @@ func NewPacman(name string) (Resource, error) {
 				Type:              "package",
 				State:             "installed",
 				Require:           make([]string, 0),
-				PresentStatesList: []string{"present", "installed"},
+				PresentStatesList: []string{"present", "installed", "latest"},
 				AbsentStatesList:  []string{"absent", "deinstalled"},
 				Concurrent:        false,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
-			Package:       name,
-			Version:       "",
-			manager:       "/usr/bin/pacman",
-			queryArgs:     []string{"--query"},
-			installArgs:   []string{"--sync", "--noconfirm"},
-			deinstallArgs: []string{"--remove", "--noconfirm"},
-			updateArgs:    []string{"--sync", "--noconfirm"},
+			Package:          name,
+			Version:          "",
+			provider:         "pacman",
+			manager:          "/usr/bin/pacman",
+			queryArgs:        []string{"--query"},
+			parseQuery:       parsePacmanQuery,
+			inventoryArgs:    []string{"--query"},
+			parseInventory:   parseNameVersion,
+			latestArgs:       []string{"--sync", "--print-format", "%v"},
+			parseLatest:      parseLatestFirstLine,
+			latestNoneStatus: 1,
+			installArgs:      []string{"--sync", "--noconfirm"},
+			deinstallArgs:    []string{"--remove", "--noconfirm"},
+			updateArgs:       []string{"--sync", "--noconfirm"},
 		},
 	}
+	p.PropertyList = []Property{p.versionProperty()}
 
 	return p, nil
 }
 
+// parsePacmanQuery parses the version of a package from the
+// output of pacman --query, e.g. "tmux 2.6-1".
+func parsePacmanQuery(name string, out []byte) (string, bool) {
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return "", false
+	}
+
+	return fields[1], true
+}
+
 // Yum type represents the resource for package management on
 // RHEL and CentOS systems.
 //
@@ -220,23 +919,59 @@ func NewYum(name string) (Resource, error) {
 				Type:              "package",
 				State:             "installed",
 				Require:           make([]string, 0),
-				PresentStatesList: []string{"present", "installed"},
+				PresentStatesList: []string{"present", "installed", "latest"},
 				AbsentStatesList:  []string{"absent", "deinstalled"},
 				Concurrent:        false,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
-			Package:       name,
-			manager:       "/usr/bin/yum",
-			queryArgs:     []string{"-q", "--noplugins", "list", "installed"},
-			installArgs:   []string{"--assumeyes", "install"},
-			deinstallArgs: []string{"--assumeyes", "remove"},
-			updateArgs:    []string{"--assumeyes", "install"},
+			Package:          name,
+			provider:         "yum",
+			manager:          "/usr/bin/yum",
+			query:            "/usr/bin/rpm",
+			queryArgs:        rpmQueryArgs,
+			parseQuery:       parseFirstLine,
+			inventoryArgs:    rpmInventoryArgs,
+			parseInventory:   parseNameVersion,
+			latestArgs:       []string{"-q", "--noplugins", "list", "updates"},
+			parseLatest:      parseYumUpdates,
+			latestNoneStatus: 1,
+			versionSpec:      joinVersion("-"),
+			installArgs:      []string{"--assumeyes", "install"},
+			deinstallArgs:    []string{"--assumeyes", "remove"},
+			updateArgs:       []string{"--assumeyes", "install"},
+			downgradeArgs:    []string{"--assumeyes", "downgrade"},
 		},
 	}
+	y.PropertyList = []Property{y.versionProperty()}
 
 	return y, nil
 }
 
+// rpmQueryArgs are the arguments used for querying the
+// installed version of a package using rpm.
+var rpmQueryArgs = []string{"--query", "--queryformat", "%{VERSION}-%{RELEASE}\n"}
+
+// rpmInventoryArgs are the arguments used for listing
+// the installed packages and their versions using rpm.
+var rpmInventoryArgs = []string{"--query", "--all", "--queryformat", "%{NAME} %{VERSION}-%{RELEASE}\n"}
+
+// parseYumUpdates parses the version of a package, which can be
+// updated from the output of yum list updates, e.g.
+// "tmux.x86_64    1.8-4.el7    base".
+func parseYumUpdates(name string, out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && strings.HasPrefix(fields[0], name+".") {
+			return fields[1]
+		}
+	}
+
+	return ""
+}
+
 // PkgNG type represents the resource for package management on
 // FreeBSD 9.2+ and DragonflyBSD 4.3+ systems.
 //
@@ -259,23 +994,343 @@ func NewPkgNG(name string) (Resource, error) {
 				Type:              "package",
 				State:             "installed",
 				Require:           make([]string, 0),
-				PresentStatesList: []string{"present", "installed"},
+				PresentStatesList: []string{"present", "installed", "latest"},
 				AbsentStatesList:  []string{"absent", "deinstalled"},
 				Concurrent:        false,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
-			Package:       name,
-			manager:       "/usr/local/sbin/pkg",
-			queryArgs:     []string{"info", "-e"},
-			installArgs:   []string{"install", "-y"},
-			deinstallArgs: []string{"remove", "-y"},
-			updateArgs:    []string{"upgrade", "-y"},
+			Package:          name,
+			provider:         "pkgng",
+			manager:          "/usr/local/sbin/pkg",
+			queryArgs:        []string{"query", "%v"},
+			parseQuery:       parseFirstLine,
+			inventoryArgs:    []string{"query", "--all", "%n %v"},
+			parseInventory:   parseNameVersion,
+			latestArgs:       []string{"rquery", "%v"},
+			parseLatest:      parseLatestFirstLine,
+			latestNoneStatus: 1,
+			versionSpec:      joinVersion("-"),
+			installArgs:      []string{"install", "-y"},
+			deinstallArgs:    []string{"remove", "-y"},
+			updateArgs:       []string{"upgrade", "-y"},
+			downgradeArgs:    []string{"install", "-y", "-f"},
 		},
 	}
+	p.PropertyList = []Property{p.versionProperty()}
 
 	return p, nil
 }
 
+// Dnf type represents the resource for package management on
+// Fedora and RHEL 8+ systems.
+//
+// Example:
+//   pkg = resource.dnf.new("emacs")
+//   pkg.state = "installed"
+type Dnf struct {
+	BasePackage
+}
+
+// NewDnf creates a new resource for managing packages
+// using the dnf package manager on Fedora and RHEL systems
+func NewDnf(name string) (Resource, error) {
+	d := &Dnf{
+		BasePackage: BasePackage{
+			Base: Base{
+				Name:              name,
+				Type:              "package",
+				State:             "installed",
+				Require:           make([]string, 0),
+				PresentStatesList: []string{"present", "installed", "latest"},
+				AbsentStatesList:  []string{"absent", "deinstalled"},
+				Concurrent:        false,
+				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
+			},
+			Package:        name,
+			provider:       "dnf",
+			manager:        "/usr/bin/dnf",
+			query:          "/usr/bin/rpm",
+			queryArgs:      rpmQueryArgs,
+			parseQuery:     parseFirstLine,
+			inventoryArgs:  rpmInventoryArgs,
+			parseInventory: parseNameVersion,
+			latestArgs:     []string{"--quiet", "repoquery", "--upgrades", "--latest-limit=1", "--queryformat", "%{version}-%{release}\n"},
+			parseLatest:    parseLatestFirstLine,
+			versionSpec:    joinVersion("-"),
+			installArgs:    []string{"--assumeyes", "install"},
+			deinstallArgs:  []string{"--assumeyes", "remove"},
+			updateArgs:     []string{"--assumeyes", "upgrade"},
+			downgradeArgs:  []string{"--assumeyes", "downgrade"},
+		},
+	}
+	d.PropertyList = []Property{d.versionProperty()}
+
+	return d, nil
+}
+
+// Apt type represents the resource for package management on
+// Debian and Ubuntu systems.
+//
+// Example:
+//   pkg = resource.apt.new("tmux")
+//   pkg.state = "installed"
+type Apt struct {
+	BasePackage
+}
+
+// NewApt creates a new resource for managing packages
+// using the apt and dpkg package managers on Debian and Ubuntu systems
+func NewApt(name string) (Resource, error) {
+	a := &Apt{
+		BasePackage: BasePackage{
+			Base: Base{
+				Name:              name,
+				Type:              "package",
+				State:             "installed",
+				Require:           make([]string, 0),
+				PresentStatesList: []string{"present", "installed", "latest"},
+				AbsentStatesList:  []string{"absent", "deinstalled"},
+				Concurrent:        false,
+				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
+			},
+			Package:        name,
+			provider:       "apt",
+			manager:        "/usr/bin/apt-get",
+			query:          "/usr/bin/dpkg-query",
+			queryArgs:      []string{"--show", "--showformat=${db:Status-Status} ${Version}\n"},
+			parseQuery:     parseDpkgQuery,
+			inventoryArgs:  []string{"--show", "--showformat=${db:Status-Status} ${Package} ${Version}\n"},
+			parseInventory: parseDpkgInventory,
+			latest:         "/usr/bin/apt-cache",
+			latestArgs:     []string{"policy"},
+			parseLatest:    parseAptPolicy,
+			versionSpec:    joinVersion("="),
+			env:            []string{"DEBIAN_FRONTEND=noninteractive"},
+			installArgs:    []string{"--assume-yes", "--allow-downgrades", "install"},
+			deinstallArgs:  []string{"--assume-yes", "remove"},
+			updateArgs:     []string{"--assume-yes", "--only-upgrade", "install"},
+		},
+	}
+	a.PropertyList = []Property{a.versionProperty()}
+
+	return a, nil
+}
+
+// parseDpkgQuery parses the package status and version reported
+// by dpkg-query. Packages which have been removed, but their
+// configuration files are still present are reported as well.
+func parseDpkgQuery(name string, out []byte) (string, bool) {
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 || fields[0] != "installed" {
+		return "", false
+	}
+
+	return fields[1], true
+}
+
+// parseDpkgInventory parses the status, name and version of
+// the packages reported by dpkg-query. Only the installed
+// packages are included in the result.
+func parseDpkgInventory(out []byte) map[string]string {
+	inventory := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "installed" {
+			inventory[fields[1]] = fields[2]
+		}
+	}
+
+	return inventory
+}
+
+// parseAptPolicy parses the candidate version of
+// a package from the output of apt-cache policy.
+func parseAptPolicy(name string, out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "Candidate:" && fields[1] != "(none)" {
+			return fields[1]
+		}
+	}
+
+	return ""
+}
+
+// Apk type represents the resource for package management on
+// Alpine Linux systems.
+//
+// Example:
+//   pkg = resource.apk.new("tmux")
+//   pkg.state = "installed"
+type Apk struct {
+	BasePackage
+}
+
+// NewApk creates a new resource for managing packages
+// using the apk package manager on Alpine Linux systems
+func NewApk(name string) (Resource, error) {
+	a := &Apk{
+		BasePackage: BasePackage{
+			Base: Base{
+				Name:              name,
+				Type:              "package",
+				State:             "installed",
+				Require:           make([]string, 0),
+				PresentStatesList: []string{"present", "installed", "latest"},
+				AbsentStatesList:  []string{"absent", "deinstalled"},
+				Concurrent:        false,
+				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
+			},
+			Package:        name,
+			provider:       "apk",
+			manager:        "/sbin/apk",
+			queryArgs:      []string{"list", "--installed"},
+			parseQuery:     parseApkList,
+			inventoryArgs:  []string{"list", "--installed"},
+			parseInventory: parseApkInventory,
+			latestArgs:     []string{"list", "--upgradable"},
+			parseLatest:    parseApkUpgradable,
+			versionSpec:    joinVersion("="),
+			installArgs:    []string{"add", "--no-progress"},
+			deinstallArgs:  []string{"del", "--no-progress"},
+			updateArgs:     []string{"add", "--upgrade", "--no-progress"},
+		},
+	}
+	a.PropertyList = []Property{a.versionProperty()}
+
+	return a, nil
+}
+
+// parseApkList parses the version of a package from the output of
+// apk list, which reports packages as name-version-release, e.g.
+// "tmux-2.6-r0 x86_64 {tmux} (ISC) [installed]".
+func parseApkList(name string, out []byte) (string, bool) {
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], name+"-") {
+			continue
+		}
+
+		// Make sure that the package name is not the prefix
+		// of another package, e.g. tmux and tmux-doc
+		version := strings.TrimPrefix(fields[0], name+"-")
+		if version != "" && version[0] >= '0' && version[0] <= '9' {
+			return version, true
+		}
+	}
+
+	return "", false
+}
+
+// parseApkInventory parses the installed packages from the output
+// of apk list. The version of a package consists of the last two
+// components of the package, e.g. "2.6-r0" for "tmux-2.6-r0".
+func parseApkInventory(out []byte) map[string]string {
+	inventory := make(map[string]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		release := strings.LastIndex(fields[0], "-")
+		if release < 1 {
+			continue
+		}
+
+		version := strings.LastIndex(fields[0][:release], "-")
+		if version < 1 {
+			continue
+		}
+
+		inventory[fields[0][:version]] = fields[0][version+1:]
+	}
+
+	return inventory
+}
+
+// parseApkUpgradable parses the version of a package, which
+// can be upgraded from the output of apk list --upgradable.
+func parseApkUpgradable(name string, out []byte) string {
+	version, _ := parseApkList(name, out)
+
+	return version
+}
+
+// Zypper type represents the resource for package management on
+// openSUSE and SUSE Linux Enterprise systems.
+//
+// Example:
+//   pkg = resource.zypper.new("tmux")
+//   pkg.state = "installed"
+type Zypper struct {
+	BasePackage
+}
+
+// NewZypper creates a new resource for managing packages
+// using the zypper package manager on openSUSE and SLES systems
+func NewZypper(name string) (Resource, error) {
+	z := &Zypper{
+		BasePackage: BasePackage{
+			Base: Base{
+				Name:              name,
+				Type:              "package",
+				State:             "installed",
+				Require:           make([]string, 0),
+				PresentStatesList: []string{"present", "installed", "latest"},
+				AbsentStatesList:  []string{"absent", "deinstalled"},
+				Concurrent:        false,
+				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
+			},
+			Package:        name,
+			provider:       "zypper",
+			manager:        "/usr/bin/zypper",
+			query:          "/usr/bin/rpm",
+			queryArgs:      rpmQueryArgs,
+			parseQuery:     parseFirstLine,
+			inventoryArgs:  rpmInventoryArgs,
+			parseInventory: parseNameVersion,
+			latestArgs:     []string{"--non-interactive", "--quiet", "info"},
+			parseLatest:    parseZypperInfo,
+			versionSpec:    joinVersion("="),
+			installArgs:    []string{"--non-interactive", "install", "--oldpackage"},
+			deinstallArgs:  []string{"--non-interactive", "remove"},
+			updateArgs:     []string{"--non-interactive", "update"},
+		},
+	}
+	z.PropertyList = []Property{z.versionProperty()}
+
+	return z, nil
+}
+
+// parseZypperInfo parses the version of a package, which
+// is available for installation from the output of zypper info.
+func parseZypperInfo(name string, out []byte) string {
+	for _, line := range strings.Split(string(out), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "Version" {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+
+	return ""
+}
+
 func init() {
 	pkg := ProviderItem{
 		Type:      "package",
@@ -299,5 +1354,29 @@ func init() {
 		Namespace: DefaultResourceNamespace,
 	}
 
-	RegisterProvider(pkg, yum, pacman, pkgng)
+	dnf := ProviderItem{
+		Type:      "dnf",
+		Provider:  NewDnf,
+		Namespace: DefaultResourceNamespace,
+	}
+
+	apt := ProviderItem{
+		Type:      "apt",
+		Provider:  NewApt,
+		Namespace: DefaultResourceNamespace,
+	}
+
+	apk := ProviderItem{
+		Type:      "apk",
+		Provider:  NewApk,
+		Namespace: DefaultResourceNamespace,
+	}
+
+	zypper := ProviderItem{
+		Type:      "zypper",
+		Provider:  NewZypper,
+		Namespace: DefaultResourceNamespace,
+	}
+
+	RegisterProvider(pkg, yum, pacman, pkgng, dnf, apt, apk, zypper)
 }
@@ -25,7 +25,15 @@
 
 package resource
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestPacman(t *testing.T) {
 	L := newLuaState()
@@ -44,7 +52,7 @@ func TestPacman(t *testing.T) {
 	errorIfNotEqual(t, "tmux", pkg.Name)
 	errorIfNotEqual(t, "installed", pkg.State)
 	errorIfNotEqual(t, []string{}, pkg.Require)
-	errorIfNotEqual(t, []string{"present", "installed"}, pkg.PresentStatesList)
+	errorIfNotEqual(t, []string{"present", "installed", "latest"}, pkg.PresentStatesList)
 	errorIfNotEqual(t, []string{"absent", "deinstalled"}, pkg.AbsentStatesList)
 	errorIfNotEqual(t, false, pkg.Concurrent)
 	errorIfNotEqual(t, "tmux", pkg.Package)
@@ -68,9 +76,368 @@ func TestYum(t *testing.T) {
 	errorIfNotEqual(t, "tmux", pkg.Name)
 	errorIfNotEqual(t, "installed", pkg.State)
 	errorIfNotEqual(t, []string{}, pkg.Require)
-	errorIfNotEqual(t, []string{"present", "installed"}, pkg.PresentStatesList)
+	errorIfNotEqual(t, []string{"present", "installed", "latest"}, pkg.PresentStatesList)
 	errorIfNotEqual(t, []string{"absent", "deinstalled"}, pkg.AbsentStatesList)
 	errorIfNotEqual(t, false, pkg.Concurrent)
 	errorIfNotEqual(t, "tmux", pkg.Package)
 	errorIfNotEqual(t, "", pkg.Version)
 }
+
+func TestApt(t *testing.T) {
+	L := newLuaState()
+	defer L.Close()
+
+	const code = `
+	tmux = resource.apt.new("tmux")
+	tmux.version = "2.6-3"
+	`
+
+	if err := L.DoString(code); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg := luaResource(L, "tmux").(*Apt)
+	errorIfNotEqual(t, "package", pkg.Type)
+	errorIfNotEqual(t, "tmux", pkg.Name)
+	errorIfNotEqual(t, "installed", pkg.State)
+	errorIfNotEqual(t, []string{}, pkg.Require)
+	errorIfNotEqual(t, []string{"present", "installed", "latest"}, pkg.PresentStatesList)
+	errorIfNotEqual(t, []string{"absent", "deinstalled"}, pkg.AbsentStatesList)
+	errorIfNotEqual(t, false, pkg.Concurrent)
+	errorIfNotEqual(t, "tmux", pkg.Package)
+	errorIfNotEqual(t, "2.6-3", pkg.Version)
+	errorIfNotEqual(t, "tmux=2.6-3", pkg.target())
+}
+
+func TestPackageProviderFor(t *testing.T) {
+	tests := []struct {
+		osRelease string
+		want      string
+	}{
+		{"ID=debian\nVERSION_ID=\"9\"\n", "*resource.Apt"},
+		{"ID=ubuntu\nID_LIKE=debian\n", "*resource.Apt"},
+		{"ID=linuxmint\nID_LIKE=\"ubuntu debian\"\n", "*resource.Apt"},
+		{"ID=fedora\n", "*resource.Dnf"},
+		{"ID=alpine\n", "*resource.Apk"},
+		{"ID=\"opensuse-leap\"\nID_LIKE=\"suse opensuse\"\n", "*resource.Zypper"},
+		{"ID=\"sles\"\n", "*resource.Zypper"},
+		{"# Arch Linux\nID=arch\n", "*resource.Pacman"},
+		{"ID=freebsd\n", "*resource.PkgNG"},
+		{"ID=unknown\n", ""},
+	}
+
+	for _, test := range tests {
+		osRelease, err := parseOSRelease(strings.NewReader(test.osRelease))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := ""
+		if provider, ok := packageProviderFor(osRelease); ok {
+			r, err := provider("tmux")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = fmt.Sprintf("%T", r)
+		}
+
+		if got != test.want {
+			t.Errorf("want %q provider for %q, got %q", test.want, test.osRelease, got)
+		}
+	}
+}
+
+func TestPackageVersion(t *testing.T) {
+	queries := []struct {
+		parse     func(name string, out []byte) (string, bool)
+		out       string
+		version   string
+		installed bool
+	}{
+		{parsePacmanQuery, "tmux 2.6-1\n", "2.6-1", true},
+		{parsePacmanQuery, "", "", false},
+		{parseFirstLine, "1.8-4.el7\n", "1.8-4.el7", true},
+		{parseDpkgQuery, "installed 1:2.6-3\n", "1:2.6-3", true},
+		{parseDpkgQuery, "config-files 2.6-3\n", "", false},
+		{parseApkList, "tmux-doc-2.6-r0 x86_64 {tmux} (ISC) [installed]\ntmux-2.6-r0 x86_64 {tmux} (ISC) [installed]\n", "2.6-r0", true},
+		{parseApkList, "", "", false},
+	}
+
+	for _, q := range queries {
+		version, installed := q.parse("tmux", []byte(q.out))
+		if version != q.version || installed != q.installed {
+			t.Errorf("want %q and %t for %q, got %q and %t", q.version, q.installed, q.out, version, installed)
+		}
+	}
+
+	latest := []struct {
+		parse   func(name string, out []byte) string
+		out     string
+		version string
+	}{
+		{parseAptPolicy, "tmux:\n  Installed: 2.6-3\n  Candidate: 2.6-4\n", "2.6-4"},
+		{parseAptPolicy, "tmux:\n  Installed: (none)\n  Candidate: (none)\n", ""},
+		{parseYumUpdates, "Updated Packages\ntmux.x86_64    1.8-4.el7    base\n", "1.8-4.el7"},
+		{parseZypperInfo, "Name           : tmux\nVersion        : 2.6-1.2\n", "2.6-1.2"},
+		{parseApkUpgradable, "tmux-2.6-r1 x86_64 {tmux} (ISC) [upgradable from: tmux-2.6-r0]\n", "2.6-r1"},
+	}
+
+	for _, l := range latest {
+		if version := l.parse("tmux", []byte(l.out)); version != l.version {
+			t.Errorf("want latest version %q for %q, got %q", l.version, l.out, version)
+		}
+	}
+
+	matches := []struct {
+		installed string
+		want      string
+		ok        bool
+	}{
+		{"2.6-3", "2.6-3", true},
+		{"2.6-3", "2.6", true},
+		{"1:2.6-3", "2.6", true},
+		{"1:2.6-3", "1:2.6-3", true},
+		{"2.6.1-1", "2.6", false},
+		{"2.5-1", "2.6", false},
+	}
+
+	for _, m := range matches {
+		if ok := versionMatches(m.installed, m.want); ok != m.ok {
+			t.Errorf("want %t for installed %q and wanted %q, got %t", m.ok, m.installed, m.want, ok)
+		}
+	}
+
+	comparisons := []struct {
+		installed string
+		want      string
+		result    int
+	}{
+		{"2.6-3", "2.6", 0},
+		{"1:2.6-3", "2.6-3", 0},
+		{"2.6-3", "2.6-4", -1},
+		{"2.10-1", "2.9", 1},
+		{"2.6.1-1", "2.6", 1},
+		{"2.6", "2.6.1", -1},
+		{"2.6a", "2.6b", -1},
+		{"2.6.1", "2.6a", 1},
+		{"2.06", "2.6", 0},
+		{"1.8-4.el7", "1.8-10.el7", -1},
+	}
+
+	for _, c := range comparisons {
+		if result := compareVersions(c.installed, c.want); result != c.result {
+			t.Errorf("want %d for installed %q and wanted %q, got %d", c.result, c.installed, c.want, result)
+		}
+	}
+}
+
+func TestPackageQuery(t *testing.T) {
+	newPackage := func(script string) *BasePackage {
+		return &BasePackage{
+			Base: Base{
+				Name:  "tmux",
+				Type:  "package",
+				State: "installed",
+			},
+			Package:     "tmux",
+			Version:     "2.6",
+			manager:     "/bin/true",
+			query:       "/bin/sh",
+			queryArgs:   []string{"-c", script, "sh"},
+			parseQuery:  parseFirstLine,
+			versionSpec: joinVersion("-"),
+		}
+	}
+
+	queries := []struct {
+		script    string
+		version   string
+		installed bool
+		err       bool
+	}{
+		{"echo 2.6-1", "2.6-1", true, false},
+		{"exit 1", "", false, false},
+		{"echo 'database is locked' >&2; exit 2", "", false, true},
+	}
+
+	for _, q := range queries {
+		version, installed, err := newPackage(q.script).installedVersion(context.Background())
+		if version != q.version || installed != q.installed || (err != nil) != q.err {
+			t.Errorf("want %q, %t and error %t for %q, got %q, %t and %v", q.version, q.installed, q.err, q.script, version, installed, err)
+		}
+	}
+
+	// The installed version is checked again after setting it
+	if err := newPackage("echo 2.5-1").setVersion(); err == nil {
+		t.Errorf("want error when the wanted version is not installed")
+	}
+}
+
+func TestPackageLatest(t *testing.T) {
+	newPackage := func(script string) *BasePackage {
+		return &BasePackage{
+			Base: Base{
+				Name:  "tmux",
+				Type:  "package",
+				State: "latest",
+			},
+			Package:          "tmux",
+			manager:          "/bin/true",
+			query:            "/bin/echo",
+			parseQuery:       parseFirstLine,
+			latest:           "/bin/sh",
+			latestArgs:       []string{"-c", script, "sh"},
+			parseLatest:      parseLatestFirstLine,
+			latestNoneStatus: 1,
+		}
+	}
+
+	queries := []struct {
+		script string
+		latest string
+		err    bool
+	}{
+		{"echo 2.7-1", "2.7-1", false},
+		{"exit 1", "", false},
+		{"echo 'repository is not reachable' >&2; exit 2", "", true},
+	}
+
+	for _, q := range queries {
+		latest, err := newPackage(q.script).latestVersion(context.Background())
+		if latest != q.latest || (err != nil) != q.err {
+			t.Errorf("want %q and error %t for %q, got %q and %v", q.latest, q.err, q.script, latest, err)
+		}
+	}
+
+	// A failed query is not mistaken for an up-to-date package
+	synced, err := newPackage("exit 2").isVersionSynced()
+	if err == nil || synced {
+		t.Errorf("want error when the latest version query fails, got %t and %v", synced, err)
+	}
+}
+
+func TestPackageVersionContext(t *testing.T) {
+	bp := &BasePackage{
+		Base: Base{
+			Name:  "tmux",
+			Type:  "package",
+			State: "installed",
+		},
+		Package:    "tmux",
+		Version:    "2.6",
+		manager:    "/bin/true",
+		query:      "/bin/sh",
+		queryArgs:  []string{"-c", "exec sleep 5", "sh"},
+		parseQuery: parseFirstLine,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The property is evaluated using the context of the execution
+	p := bp.versionProperty().(ContextProperty)
+	if _, err := p.IsSyncedContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("want %v when evaluating the version, got %v", context.DeadlineExceeded, err)
+	}
+
+	if _, _, err := bp.versionProperty().(ContextPropertyValuer).ValuesContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("want %v when retrieving the versions, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestPackageValidate(t *testing.T) {
+	L := newLuaState()
+	defer L.Close()
+
+	const code = `
+	pinned = resource.pacman.new("tmux")
+	pinned.version = "2.6-1"
+
+	latest = resource.yum.new("tmux")
+	latest.state = "latest"
+	latest.version = "1.8"
+	`
+
+	if err := L.DoString(code); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"pinned", "latest"} {
+		if err := luaResource(L, name).(Resource).Validate(); err == nil {
+			t.Errorf("want validation error for %s, got nil", name)
+		}
+	}
+}
+
+func TestPackageInventory(t *testing.T) {
+	tests := []struct {
+		parse func(out []byte) map[string]string
+		out   string
+		want  map[string]string
+	}{
+		{
+			parseNameVersion,
+			"tmux 2.6-1\nkernel 3.10.0-693.el7\nkernel 3.10.0-514.el7\n",
+			map[string]string{"tmux": "2.6-1", "kernel": "3.10.0-693.el7"},
+		},
+		{
+			parseDpkgInventory,
+			"installed tmux 2.6-3\nconfig-files vim 2:8.0-1\ninstalled libc6 2.24-11\n",
+			map[string]string{"tmux": "2.6-3", "libc6": "2.24-11"},
+		},
+		{
+			parseApkInventory,
+			"tmux-2.6-r0 x86_64 {tmux} (ISC) [installed]\nca-certificates-20171114-r0 x86_64 {ca-certificates} (MPL-2.0 GPL-2.0+) [installed]\n",
+			map[string]string{"tmux": "2.6-r0", "ca-certificates": "20171114-r0"},
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.parse([]byte(test.out)); !reflect.DeepEqual(test.want, got) {
+			t.Errorf("want %v inventory for %q, got %v", test.want, test.out, got)
+		}
+	}
+}
+
+func TestPackageSpec(t *testing.T) {
+	providers := map[string]Provider{
+		"pacman": NewPacman,
+		"yum":    NewYum,
+		"pkgng":  NewPkgNG,
+		"dnf":    NewDnf,
+		"apt":    NewApt,
+		"apk":    NewApk,
+		"zypper": NewZypper,
+	}
+
+	for name, provider := range providers {
+		r, err := provider("tmux")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		spec, err := NewSpec(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		errorIfNotEqual(t, "package[tmux]", spec.ID())
+		errorIfNotEqual(t, name, spec.Provider)
+
+		// The package is rebuilt using the same package manager
+		data, err := json.Marshal(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var compiled Spec
+		if err := json.Unmarshal(data, &compiled); err != nil {
+			t.Fatal(err)
+		}
+
+		rebuilt, err := compiled.Resource()
+		if err != nil {
+			t.Fatal(err)
+		}
+		errorIfNotEqual(t, reflect.TypeOf(r), reflect.TypeOf(rebuilt))
+	}
+}
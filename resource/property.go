@@ -25,6 +25,8 @@
 
 package resource
 
+import "context"
+
 // Property type represents a resource property, which can be
 // evaluated and set if needed.
 type Property interface {
@@ -39,16 +41,66 @@ type Property interface {
 	IsSynced() (bool, error)
 }
 
+// ContextProperty is an optional interface type implemented by
+// properties, which support cancellation when being evaluated or set.
+type ContextProperty interface {
+	// SetContext sets the property to it's desired state.
+	SetContext(ctx context.Context) error
+
+	// IsSyncedContext returns a boolean indicating whether the
+	// resource property is in sync or not.
+	IsSyncedContext(ctx context.Context) (bool, error)
+}
+
+// PropertyValuer is an optional interface type implemented by
+// properties, which are able to report their current and
+// desired values.
+type PropertyValuer interface {
+	// Values returns the current and desired values of the property.
+	Values() (current interface{}, want interface{}, err error)
+}
+
+// ContextPropertyValuer is an optional interface type implemented
+// by properties, which support cancellation when reporting their
+// current and desired values.
+type ContextPropertyValuer interface {
+	// ValuesContext returns the current and desired values of the property.
+	ValuesContext(ctx context.Context) (current interface{}, want interface{}, err error)
+}
+
 // ResourceProperty type implements the Property interface.
 type ResourceProperty struct {
 	// PropertySetFunc is the type of the function that is called when
 	// setting a resource property to it's desired state.
 	PropertySetFunc func() error
 
+	// PropertySetContextFunc is the type of the function that is called
+	// when setting a resource property to it's desired state using a
+	// context. This function is optional and if it is not provided,
+	// then PropertySetFunc is used instead.
+	PropertySetContextFunc func(ctx context.Context) error
+
 	// PropertyIsSyncedFunc is the type of the function that is called when
 	// determining whether a resource property is in the desired state.
 	PropertyIsSyncedFunc func() (bool, error)
 
+	// PropertyIsSyncedContextFunc is the type of the function that is
+	// called when determining whether a resource property is in the
+	// desired state using a context. This function is optional and if
+	// it is not provided, then PropertyIsSyncedFunc is used instead.
+	PropertyIsSyncedContextFunc func(ctx context.Context) (bool, error)
+
+	// PropertyValuesFunc is the type of the function that is called when
+	// retrieving the current and desired values of a resource property.
+	// This function is optional.
+	PropertyValuesFunc func() (interface{}, interface{}, error)
+
+	// PropertyValuesContextFunc is the type of the function that is
+	// called when retrieving the current and desired values of a
+	// resource property using a context. This function is optional
+	// and if it is not provided, then PropertyValuesFunc is used instead.
+	PropertyValuesContextFunc func(ctx context.Context) (interface{}, interface{}, error)
+
 	// PropertyName is the name of the property.
 	PropertyName string
 }
@@ -58,13 +110,54 @@ func (rp *ResourceProperty) Set() error {
 	return rp.PropertySetFunc()
 }
 
+// SetContext sets the property to it's desired state using the
+// given context.
+func (rp *ResourceProperty) SetContext(ctx context.Context) error {
+	if rp.PropertySetContextFunc == nil {
+		return rp.PropertySetFunc()
+	}
+
+	return rp.PropertySetContextFunc(ctx)
+}
+
 // IsSynced returns a boolean indicating whether the
 // resource property is in the desired state.
 func (rp *ResourceProperty) IsSynced() (bool, error) {
 	return rp.PropertyIsSyncedFunc()
 }
 
+// IsSyncedContext returns a boolean indicating whether the resource
+// property is in the desired state using the given context.
+func (rp *ResourceProperty) IsSyncedContext(ctx context.Context) (bool, error) {
+	if rp.PropertyIsSyncedContextFunc == nil {
+		return rp.PropertyIsSyncedFunc()
+	}
+
+	return rp.PropertyIsSyncedContextFunc(ctx)
+}
+
 // Name returns the property name.
 func (rp *ResourceProperty) Name() string {
 	return rp.PropertyName
 }
+
+// Values returns the current and desired values of the property.
+// If the property does not provide a function for retrieving it's
+// values, then nil values are returned.
+func (rp *ResourceProperty) Values() (interface{}, interface{}, error) {
+	if rp.PropertyValuesFunc == nil {
+		return nil, nil, nil
+	}
+
+	return rp.PropertyValuesFunc()
+}
+
+// ValuesContext returns the current and desired values of the
+// property using the given context.
+func (rp *ResourceProperty) ValuesContext(ctx context.Context) (interface{}, interface{}, error) {
+	if rp.PropertyValuesContextFunc == nil {
+		return rp.Values()
+	}
+
+	return rp.PropertyValuesContextFunc(ctx)
+}
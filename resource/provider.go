@@ -25,6 +25,8 @@
 
 package resource
 
+import "fmt"
+
 // providerRegistry contains the registered providers
 var providerRegistry = make([]ProviderItem, 0)
 
@@ -49,3 +51,15 @@ type ProviderItem struct {
 func RegisterProvider(items ...ProviderItem) {
 	providerRegistry = append(providerRegistry, items...)
 }
+
+// NewResource creates a new resource of the given type by using
+// the provider registered for the type.
+func NewResource(typ, name string) (Resource, error) {
+	for _, item := range providerRegistry {
+		if item.Type == typ {
+			return item.Provider(name)
+		}
+	}
+
+	return nil, fmt.Errorf("No provider registered for type %s", typ)
+}
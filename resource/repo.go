@@ -0,0 +1,896 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build !windows
+
+package resource
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dnaeon/gru/utils"
+)
+
+// ErrKeyFingerprintMismatch is returned when the fingerprint of
+// a repository signing key does not match the expected one
+var ErrKeyFingerprintMismatch = errors.New("Signing key fingerprint mismatch")
+
+// keyFetchTimeout is the maximum amount of time
+// fetching a signing key from a URL may take
+const keyFetchTimeout = 30 * time.Second
+
+// BaseRepo is the base resource type for managing package repositories.
+// It's purpose is to be embedded into other repository resource providers.
+//
+// A repository is described by a definition file and optionally a
+// signing key, which is used for verifying the packages from the
+// repository. Package metadata is refreshed whenever the definition
+// file or the signing key of the repository has changed.
+type BaseRepo struct {
+	Base
+
+	// Path to the repository definition file
+	Path string `luar:"-"`
+
+	// Location of the signing key. Can be either a http(s) URL or
+	// a path to a file in the site repo. Keys fetched over plain
+	// http require the fingerprint of the key to be set.
+	Key string `luar:"key"`
+
+	// Expected fingerprint of the signing key. If set the signing
+	// key is verified before it is installed.
+	KeyFingerprint string `luar:"key_fingerprint"`
+
+	// Path to the installed signing key
+	KeyPath string `luar:"-"`
+
+	// Content of the signing key
+	keyData []byte `luar:"-"`
+
+	// Function used to create the content of the definition file
+	render func() []byte `luar:"-"`
+
+	// Function used to compute the fingerprint of the signing key
+	fingerprint func(ctx context.Context, key []byte) ([]string, error) `luar:"-"`
+
+	// Function used to convert the signing key to the format
+	// expected by the package manager. The key is installed
+	// as is if not set.
+	convertKey func(key []byte) ([]byte, error) `luar:"-"`
+
+	// Command used to import the signing key after it is installed.
+	// The path to the key is appended to the command.
+	importCmd []string `luar:"-"`
+
+	// Function used to remove an imported signing key, which is
+	// called with the content of the installed signing key
+	removeKey func(ctx context.Context, key []byte) error `luar:"-"`
+
+	// Command used to refresh the package metadata of the repository
+	refreshCmd []string `luar:"-"`
+
+	// Command used to refresh the package metadata of all
+	// repositories, which is used once the repository has
+	// been removed and can no longer be refreshed on it's own
+	refreshAllCmd []string `luar:"-"`
+}
+
+// Validate validates the repository resource
+func (br *BaseRepo) Validate() error {
+	if err := br.Base.Validate(); err != nil {
+		return err
+	}
+
+	if br.KeyFingerprint != "" && br.Key == "" {
+		return errors.New("cannot use 'key_fingerprint' without 'key'")
+	}
+
+	if strings.HasPrefix(br.Key, "http://") && br.KeyFingerprint == "" {
+		return errors.New("cannot fetch 'key' over http without 'key_fingerprint'")
+	}
+
+	return nil
+}
+
+// Initialize fetches and verifies the signing key of the repository
+func (br *BaseRepo) Initialize() error {
+	return br.InitializeContext(context.Background())
+}
+
+// InitializeContext fetches and verifies the signing
+// key of the repository using the given context
+func (br *BaseRepo) InitializeContext(ctx context.Context) error {
+	if br.Key == "" {
+		return nil
+	}
+
+	key, err := fetchKey(ctx, br.Key)
+	if err != nil {
+		return err
+	}
+
+	if br.KeyFingerprint != "" {
+		fingerprints, err := br.fingerprint(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		want := normalizeFingerprint(br.KeyFingerprint)
+		found := false
+		for _, fpr := range fingerprints {
+			if normalizeFingerprint(fpr) == want {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("%s: %s", ErrKeyFingerprintMismatch, br.Key)
+		}
+	}
+
+	if br.convertKey != nil {
+		key, err = br.convertKey(key)
+		if err != nil {
+			return err
+		}
+	}
+	br.keyData = key
+
+	return nil
+}
+
+// Evaluate evaluates the state of the repository
+func (br *BaseRepo) Evaluate() (State, error) {
+	state := State{
+		Current: "unknown",
+		Want:    br.State,
+	}
+
+	if utils.NewFileUtil(br.Path).Exists() {
+		state.Current = "present"
+	} else {
+		state.Current = "absent"
+	}
+
+	return state, nil
+}
+
+// Create installs the signing key and the definition file of the
+// repository and refreshes the package metadata.
+func (br *BaseRepo) Create() error {
+	return br.CreateContext(context.Background())
+}
+
+// CreateContext installs the signing key and the definition file of
+// the repository and refreshes the package metadata using the given
+// context.
+func (br *BaseRepo) CreateContext(ctx context.Context) error {
+	Logf("%s creating repository\n", br.ID())
+
+	if err := br.installKey(ctx); err != nil {
+		return err
+	}
+
+	if err := br.writeDefinition(); err != nil {
+		return err
+	}
+
+	return br.RefreshContext(ctx)
+}
+
+// Delete removes the definition file and the signing
+// key of the repository and refreshes the package metadata.
+func (br *BaseRepo) Delete() error {
+	return br.DeleteContext(context.Background())
+}
+
+// DeleteContext removes the definition file and the signing key of
+// the repository and refreshes the package metadata using the given
+// context.
+func (br *BaseRepo) DeleteContext(ctx context.Context) error {
+	Logf("%s removing repository\n", br.ID())
+
+	if err := os.Remove(br.Path); err != nil {
+		return err
+	}
+
+	// The signing key is removed even if the repository
+	// no longer uses it, e.g. when purging the repository
+	if err := br.uninstallKey(ctx); err != nil {
+		return err
+	}
+
+	return br.RefreshContext(ctx)
+}
+
+// Refresh refreshes the package metadata of the repository
+func (br *BaseRepo) Refresh() error {
+	return br.RefreshContext(context.Background())
+}
+
+// RefreshContext refreshes the package metadata
+// of the repository using the given context
+func (br *BaseRepo) RefreshContext(ctx context.Context) error {
+	Logf("%s refreshing package metadata\n", br.ID())
+
+	return br.run(ctx, br.refreshCommand())
+}
+
+// refreshCommand returns the command used for refreshing the package
+// metadata. Package managers refuse to refresh a repository, which is
+// not defined, so the metadata of all repositories is refreshed once
+// the repository has been removed.
+func (br *BaseRepo) refreshCommand() []string {
+	if !utils.NewFileUtil(br.Path).Exists() {
+		return br.refreshAllCmd
+	}
+
+	return br.refreshCmd
+}
+
+// run executes the given command and logs it's output
+func (br *BaseRepo) run(ctx context.Context, command []string) error {
+	out, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput()
+	for _, line := range strings.Split(string(out), "\n") {
+		Logf("%s %s\n", br.ID(), line)
+	}
+
+	return err
+}
+
+// writeDefinition writes the definition file of the repository
+func (br *BaseRepo) writeDefinition() error {
+	if err := os.MkdirAll(filepath.Dir(br.Path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(br.Path, br.render(), 0644)
+}
+
+// installKey installs and imports the signing key of the repository
+func (br *BaseRepo) installKey(ctx context.Context) error {
+	if br.Key == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(br.KeyPath), 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(br.KeyPath, br.keyData, 0644); err != nil {
+		return err
+	}
+
+	if len(br.importCmd) == 0 {
+		return nil
+	}
+
+	return br.run(ctx, append(append([]string{}, br.importCmd...), br.KeyPath))
+}
+
+// uninstallKey removes the installed signing key of
+// the repository along with the imported signing key
+func (br *BaseRepo) uninstallKey(ctx context.Context) error {
+	key, err := ioutil.ReadFile(br.KeyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if br.removeKey != nil {
+		if err := br.removeKey(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return os.Remove(br.KeyPath)
+}
+
+// isDefinitionSynced checks whether the definition file
+// of the repository is up-to-date.
+func (br *BaseRepo) isDefinitionSynced() (bool, error) {
+	current, err := ioutil.ReadFile(br.Path)
+	if os.IsNotExist(err) {
+		return false, ErrResourceAbsent
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(current, br.render()), nil
+}
+
+// setDefinition updates the definition file of the
+// repository and refreshes the package metadata.
+func (br *BaseRepo) setDefinition() error {
+	return br.setDefinitionContext(context.Background())
+}
+
+// setDefinitionContext updates the definition file of the repository
+// and refreshes the package metadata using the given context.
+func (br *BaseRepo) setDefinitionContext(ctx context.Context) error {
+	Logf("%s updating repository definition\n", br.ID())
+
+	if err := br.writeDefinition(); err != nil {
+		return err
+	}
+
+	return br.RefreshContext(ctx)
+}
+
+// isKeySynced checks whether the installed signing key
+// of the repository is up-to-date.
+func (br *BaseRepo) isKeySynced() (bool, error) {
+	// We don't have a key, assume key is correct
+	if br.Key == "" {
+		return true, nil
+	}
+
+	if !utils.NewFileUtil(br.Path).Exists() {
+		return false, ErrResourceAbsent
+	}
+
+	current, err := ioutil.ReadFile(br.KeyPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(current, br.keyData), nil
+}
+
+// setKey replaces the signing key of the repository
+// and refreshes the package metadata.
+func (br *BaseRepo) setKey() error {
+	return br.setKeyContext(context.Background())
+}
+
+// setKeyContext replaces the signing key of the repository
+// and refreshes the package metadata using the given context.
+func (br *BaseRepo) setKeyContext(ctx context.Context) error {
+	Logf("%s updating signing key\n", br.ID())
+
+	if err := br.uninstallKey(ctx); err != nil {
+		return err
+	}
+
+	if err := br.installKey(ctx); err != nil {
+		return err
+	}
+
+	return br.RefreshContext(ctx)
+}
+
+// repoProperties returns the properties of a repository resource
+func (br *BaseRepo) repoProperties() []Property {
+	properties := []Property{
+		&ResourceProperty{
+			PropertyName:           "definition",
+			PropertySetFunc:        br.setDefinition,
+			PropertySetContextFunc: br.setDefinitionContext,
+			PropertyIsSyncedFunc:   br.isDefinitionSynced,
+		},
+		&ResourceProperty{
+			PropertyName:           "key",
+			PropertySetFunc:        br.setKey,
+			PropertySetContextFunc: br.setKeyContext,
+			PropertyIsSyncedFunc:   br.isKeySynced,
+		},
+	}
+
+	return properties
+}
+
+// fetchKey fetches a signing key from the given location, which
+// is either a http(s) URL or a path to a file in the site repo.
+func fetchKey(ctx context.Context, location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return ioutil.ReadFile(filepath.Join(DefaultConfig.SiteRepo, location))
+	}
+
+	req, err := http.NewRequest("GET", location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: keyFetchTimeout}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch %s: %s", location, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// normalizeFingerprint removes the whitespace from a
+// fingerprint and converts it to upper case.
+func normalizeFingerprint(fpr string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(fpr), ""))
+}
+
+// gpgFingerprints returns the fingerprints of the OpenPGP keys
+func gpgFingerprints(ctx context.Context, key []byte) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "gpg", "--batch", "--with-colons", "--import-options", "show-only", "--import")
+	cmd.Stdin = bytes.NewReader(key)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseGPGFingerprints(out), nil
+}
+
+// parseGPGFingerprints parses the fingerprints of the primary
+// keys from the colon-delimited output of gpg(1).
+func parseGPGFingerprints(out []byte) []string {
+	var fingerprints []string
+	primary := false
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, ":")
+		switch {
+		case fields[0] == "pub":
+			primary = true
+		case fields[0] == "sub":
+			primary = false
+		case fields[0] == "fpr" && primary && len(fields) > 9:
+			fingerprints = append(fingerprints, fields[9])
+			primary = false
+		}
+	}
+
+	return fingerprints
+}
+
+// removeRPMKey removes the given OpenPGP key from the rpm database,
+// where imported keys are stored as gpg-pubkey packages named after
+// the short id of the key, e.g. gpg-pubkey-352c64e5.
+func (br *BaseRepo) removeRPMKey(ctx context.Context, key []byte) error {
+	fingerprints, err := gpgFingerprints(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	for _, fpr := range fingerprints {
+		fpr = normalizeFingerprint(fpr)
+		if len(fpr) < 8 {
+			continue
+		}
+
+		name := "gpg-pubkey-" + strings.ToLower(fpr[len(fpr)-8:])
+		if err := exec.CommandContext(ctx, "/usr/bin/rpm", "--query", "--quiet", name).Run(); err != nil {
+			// Key has not been imported
+			continue
+		}
+
+		if err := br.run(ctx, []string{"/usr/bin/rpm", "--erase", "--allmatches", name}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dearmorKey converts an ASCII armored OpenPGP key to it's binary form,
+// which is the format of the keyrings used by apt. Binary keys are
+// returned as is.
+func dearmorKey(key []byte) ([]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(key), []byte("-----BEGIN PGP")) {
+		return key, nil
+	}
+
+	// The armored key consists of a header line, optional
+	// headers followed by an empty line, the base64 encoded
+	// key, an optional checksum and a footer line
+	var body []string
+	inBody := false
+	lines := strings.Split(strings.Replace(string(key), "\r\n", "\n", -1), "\n")
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "-----END PGP"):
+			return base64.StdEncoding.DecodeString(strings.Join(body, ""))
+		case !inBody:
+			inBody = line == ""
+		case strings.HasPrefix(line, "="):
+			continue
+		default:
+			body = append(body, line)
+		}
+	}
+
+	return nil, errors.New("invalid armored OpenPGP key")
+}
+
+// sha256Fingerprint returns the SHA256 checksum of the key,
+// which is used as a fingerprint by pkg(8)
+func sha256Fingerprint(ctx context.Context, key []byte) ([]string, error) {
+	return []string{fmt.Sprintf("%x", sha256.Sum256(key))}, nil
+}
+
+// YumRepo type represents the resource for managing yum
+// repositories on RHEL, CentOS and Fedora systems.
+//
+// Example:
+//   epel = resource.yum_repo.new("epel")
+//   epel.description = "Extra Packages for Enterprise Linux 7"
+//   epel.mirrorlist = "https://mirrors.fedoraproject.org/metalink?repo=epel-7&arch=$basearch"
+//   epel.key = "https://dl.fedoraproject.org/pub/epel/RPM-GPG-KEY-EPEL-7"
+//   epel.key_fingerprint = "91E9 7D7C 4A5E 96F1 7F3E 888F 6A2F AEA2 352C 64E5"
+type YumRepo struct {
+	BaseRepo
+
+	// Description of the repository
+	Description string `luar:"description"`
+
+	// Base URL of the repository
+	BaseURL string `luar:"baseurl"`
+
+	// URL of the repository mirror list
+	MirrorList string `luar:"mirrorlist"`
+
+	// Enabled flag indicates whether the repository is enabled
+	Enabled bool `luar:"enabled"`
+}
+
+// NewYumRepo creates a new resource for managing yum repositories
+func NewYumRepo(name string) (Resource, error) {
+	y := &YumRepo{
+		BaseRepo: BaseRepo{
+			Base: Base{
+				Name:              name,
+				Type:              "yum_repo",
+				State:             "present",
+				Require:           make([]string, 0),
+				PresentStatesList: []string{"present"},
+				AbsentStatesList:  []string{"absent"},
+				Concurrent:        false,
+				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
+			},
+			Path:          filepath.Join("/etc/yum.repos.d", name+".repo"),
+			KeyPath:       filepath.Join("/etc/pki/rpm-gpg", "RPM-GPG-KEY-"+name),
+			fingerprint:   gpgFingerprints,
+			importCmd:     []string{"/usr/bin/rpm", "--import"},
+			refreshCmd:    []string{"/usr/bin/yum", "--quiet", "--disablerepo=*", "--enablerepo=" + name, "makecache"},
+			refreshAllCmd: []string{"/usr/bin/yum", "--quiet", "makecache"},
+		},
+		Description: name,
+		Enabled:     true,
+	}
+	y.render = y.definition
+	y.removeKey = y.removeRPMKey
+	y.PropertyList = y.repoProperties()
+
+	return y, nil
+}
+
+// Validate validates the yum repository resource
+func (y *YumRepo) Validate() error {
+	if err := y.BaseRepo.Validate(); err != nil {
+		return err
+	}
+
+	if y.wantAbsent() {
+		return nil
+	}
+
+	if y.BaseURL == "" && y.MirrorList == "" {
+		return errors.New("either 'baseurl' or 'mirrorlist' must be set")
+	}
+
+	return nil
+}
+
+// definition creates the content of the repository definition file
+func (y *YumRepo) definition() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "[%s]\n", y.Name)
+	fmt.Fprintf(&buf, "name=%s\n", y.Description)
+	if y.BaseURL != "" {
+		fmt.Fprintf(&buf, "baseurl=%s\n", y.BaseURL)
+	}
+	if y.MirrorList != "" {
+		fmt.Fprintf(&buf, "mirrorlist=%s\n", y.MirrorList)
+	}
+	fmt.Fprintf(&buf, "enabled=%d\n", boolToInt(y.Enabled))
+	if y.Key != "" {
+		fmt.Fprintf(&buf, "gpgcheck=1\n")
+		fmt.Fprintf(&buf, "gpgkey=file://%s\n", y.KeyPath)
+	} else {
+		fmt.Fprintf(&buf, "gpgcheck=0\n")
+	}
+
+	return buf.Bytes()
+}
+
+// AptSource type represents the resource for managing apt
+// sources on Debian and Ubuntu systems.
+//
+// Example:
+//   docker = resource.apt_source.new("docker")
+//   docker.uri = "https://download.docker.com/linux/debian"
+//   docker.distribution = "stretch"
+//   docker.components = { "stable" }
+//   docker.key = "https://download.docker.com/linux/debian/gpg"
+//   docker.key_fingerprint = "9DC8 5822 9FC7 DD38 854A E2D8 8D81 803C 0EBF CD88"
+type AptSource struct {
+	BaseRepo
+
+	// URI of the repository
+	URI string `luar:"uri"`
+
+	// Distribution of the repository, e.g. stretch
+	Distribution string `luar:"distribution"`
+
+	// Components of the repository, e.g. main and contrib
+	Components []string `luar:"components"`
+
+	// Architectures for which packages are fetched.
+	// Defaults to the architectures configured for dpkg.
+	Architectures []string `luar:"architectures"`
+
+	// Source flag indicates whether source packages
+	// are fetched from the repository as well
+	Source bool `luar:"source"`
+}
+
+// NewAptSource creates a new resource for managing apt sources
+func NewAptSource(name string) (Resource, error) {
+	a := &AptSource{
+		BaseRepo: BaseRepo{
+			Base: Base{
+				Name:              name,
+				Type:              "apt_source",
+				State:             "present",
+				Require:           make([]string, 0),
+				PresentStatesList: []string{"present"},
+				AbsentStatesList:  []string{"absent"},
+				Concurrent:        false,
+				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
+			},
+			Path:        filepath.Join("/etc/apt/sources.list.d", name+".list"),
+			KeyPath:     filepath.Join("/etc/apt/keyrings", name+".gpg"),
+			fingerprint: gpgFingerprints,
+			convertKey:  dearmorKey,
+			refreshCmd: []string{
+				"/usr/bin/apt-get", "update",
+				"-o", "Dir::Etc::sourcelist=" + filepath.Join("sources.list.d", name+".list"),
+				"-o", "Dir::Etc::sourceparts=-",
+				"-o", "APT::Get::List-Cleanup=0",
+			},
+			refreshAllCmd: []string{"/usr/bin/apt-get", "update"},
+		},
+		Components:    []string{"main"},
+		Architectures: make([]string, 0),
+		Source:        false,
+	}
+	a.render = a.definition
+	a.PropertyList = a.repoProperties()
+
+	return a, nil
+}
+
+// Validate validates the apt source resource
+func (a *AptSource) Validate() error {
+	if err := a.BaseRepo.Validate(); err != nil {
+		return err
+	}
+
+	if a.wantAbsent() {
+		return nil
+	}
+
+	if a.URI == "" || a.Distribution == "" {
+		return errors.New("both 'uri' and 'distribution' must be set")
+	}
+
+	return nil
+}
+
+// definition creates the content of the sources list file. The signing
+// key of the source is trusted only for the packages from the source.
+func (a *AptSource) definition() []byte {
+	var opts []string
+	if len(a.Architectures) > 0 {
+		opts = append(opts, "arch="+strings.Join(a.Architectures, ","))
+	}
+	if a.Key != "" {
+		opts = append(opts, "signed-by="+a.KeyPath)
+	}
+
+	var options string
+	if len(opts) > 0 {
+		options = fmt.Sprintf("[%s] ", strings.Join(opts, " "))
+	}
+
+	entry := strings.Join(append([]string{a.URI, a.Distribution}, a.Components...), " ")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "deb %s%s\n", options, entry)
+	if a.Source {
+		fmt.Fprintf(&buf, "deb-src %s%s\n", options, entry)
+	}
+
+	return buf.Bytes()
+}
+
+// PkgNGRepo type represents the resource for managing pkg(8)
+// repositories on FreeBSD systems.
+//
+// The signing key of the repository is a public key, and it's
+// fingerprint is the SHA256 checksum of the key.
+//
+// Example:
+//   myrepo = resource.pkgng_repo.new("myrepo")
+//   myrepo.url = "pkg+http://pkg.example.org/${ABI}/latest"
+//   myrepo.mirror_type = "srv"
+//   myrepo.key = "keys/pkg.example.org.pub"
+type PkgNGRepo struct {
+	BaseRepo
+
+	// URL of the repository
+	URL string `luar:"url"`
+
+	// Mirror type of the repository - srv, http or none
+	MirrorType string `luar:"mirror_type"`
+
+	// Enabled flag indicates whether the repository is enabled
+	Enabled bool `luar:"enabled"`
+}
+
+// NewPkgNGRepo creates a new resource for managing pkg(8) repositories
+func NewPkgNGRepo(name string) (Resource, error) {
+	p := &PkgNGRepo{
+		BaseRepo: BaseRepo{
+			Base: Base{
+				Name:              name,
+				Type:              "pkgng_repo",
+				State:             "present",
+				Require:           make([]string, 0),
+				PresentStatesList: []string{"present"},
+				AbsentStatesList:  []string{"absent"},
+				Concurrent:        false,
+				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
+			},
+			Path:          filepath.Join("/usr/local/etc/pkg/repos", name+".conf"),
+			KeyPath:       filepath.Join("/usr/local/etc/pkg/keys", name+".pub"),
+			fingerprint:   sha256Fingerprint,
+			refreshCmd:    []string{"/usr/local/sbin/pkg", "update", "-f", "-r", name},
+			refreshAllCmd: []string{"/usr/local/sbin/pkg", "update", "-f"},
+		},
+		MirrorType: "none",
+		Enabled:    true,
+	}
+	p.render = p.definition
+	p.PropertyList = p.repoProperties()
+
+	return p, nil
+}
+
+// Validate validates the pkg(8) repository resource
+func (p *PkgNGRepo) Validate() error {
+	if err := p.BaseRepo.Validate(); err != nil {
+		return err
+	}
+
+	if p.wantAbsent() {
+		return nil
+	}
+
+	if p.URL == "" {
+		return errors.New("'url' must be set")
+	}
+
+	mirrorTypes := utils.NewList("srv", "http", "none")
+	if !mirrorTypes.Contains(p.MirrorType) {
+		return fmt.Errorf("invalid mirror type '%s'", p.MirrorType)
+	}
+
+	return nil
+}
+
+// definition creates the content of the repository configuration file
+func (p *PkgNGRepo) definition() []byte {
+	enabled := "no"
+	if p.Enabled {
+		enabled = "yes"
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s: {\n", p.Name)
+	fmt.Fprintf(&buf, "  url: %q,\n", p.URL)
+	fmt.Fprintf(&buf, "  mirror_type: %q,\n", p.MirrorType)
+	if p.Key != "" {
+		fmt.Fprintf(&buf, "  signature_type: \"pubkey\",\n")
+		fmt.Fprintf(&buf, "  pubkey: %q,\n", p.KeyPath)
+	} else {
+		fmt.Fprintf(&buf, "  signature_type: \"none\",\n")
+	}
+	fmt.Fprintf(&buf, "  enabled: %s\n", enabled)
+	fmt.Fprintf(&buf, "}\n")
+
+	return buf.Bytes()
+}
+
+// boolToInt converts a boolean to 1 or 0
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func init() {
+	yumRepo := ProviderItem{
+		Type:      "yum_repo",
+		Provider:  NewYumRepo,
+		Namespace: DefaultResourceNamespace,
+	}
+
+	aptSource := ProviderItem{
+		Type:      "apt_source",
+		Provider:  NewAptSource,
+		Namespace: DefaultResourceNamespace,
+	}
+
+	pkgngRepo := ProviderItem{
+		Type:      "pkgng_repo",
+		Provider:  NewPkgNGRepo,
+		Namespace: DefaultResourceNamespace,
+	}
+
+	RegisterProvider(yumRepo, aptSource, pkgngRepo)
+}
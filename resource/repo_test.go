@@ -0,0 +1,341 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+// +build !windows
+
+package resource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestYumRepo(t *testing.T) {
+	L := newLuaState()
+	defer L.Close()
+
+	const code = `
+	epel = resource.yum_repo.new("epel")
+	epel.description = "Extra Packages for Enterprise Linux 7"
+	epel.mirrorlist = "https://mirrors.example.org/metalink?repo=epel-7&arch=$basearch"
+	epel.key = "keys/RPM-GPG-KEY-EPEL-7"
+	`
+
+	if err := L.DoString(code); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := luaResource(L, "epel").(*YumRepo)
+	errorIfNotEqual(t, "yum_repo", repo.Type)
+	errorIfNotEqual(t, "epel", repo.Name)
+	errorIfNotEqual(t, "present", repo.State)
+	errorIfNotEqual(t, []string{}, repo.Require)
+	errorIfNotEqual(t, []string{"present"}, repo.PresentStatesList)
+	errorIfNotEqual(t, []string{"absent"}, repo.AbsentStatesList)
+	errorIfNotEqual(t, false, repo.Concurrent)
+	errorIfNotEqual(t, "/etc/yum.repos.d/epel.repo", repo.Path)
+	errorIfNotEqual(t, "/etc/pki/rpm-gpg/RPM-GPG-KEY-epel", repo.KeyPath)
+	errorIfNotEqual(t, true, repo.Enabled)
+
+	want := `[epel]
+name=Extra Packages for Enterprise Linux 7
+mirrorlist=https://mirrors.example.org/metalink?repo=epel-7&arch=$basearch
+enabled=1
+gpgcheck=1
+gpgkey=file:///etc/pki/rpm-gpg/RPM-GPG-KEY-epel
+`
+	errorIfNotEqual(t, want, string(repo.definition()))
+
+	repo.Key = "http://mirrors.example.org/RPM-GPG-KEY-EPEL-7"
+	if err := repo.Validate(); err == nil {
+		t.Error("expected validation error for key fetched over http without fingerprint")
+	}
+
+	repo.KeyFingerprint = "91E9 7D7C 4A5E 96F1 7F3E 888F 6A2F AEA2 352C 64E5"
+	if err := repo.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	repo.MirrorList = ""
+	if err := repo.Validate(); err == nil {
+		t.Error("expected validation error for repository without baseurl and mirrorlist")
+	}
+}
+
+func TestAptSource(t *testing.T) {
+	L := newLuaState()
+	defer L.Close()
+
+	const code = `
+	docker = resource.apt_source.new("docker")
+	docker.uri = "https://download.example.org/linux/debian"
+	docker.distribution = "stretch"
+	docker.components = { "stable", "edge" }
+	docker.architectures = { "amd64" }
+	docker.source = true
+	`
+
+	if err := L.DoString(code); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := luaResource(L, "docker").(*AptSource)
+	errorIfNotEqual(t, "apt_source", repo.Type)
+	errorIfNotEqual(t, "docker", repo.Name)
+	errorIfNotEqual(t, "present", repo.State)
+	errorIfNotEqual(t, "/etc/apt/sources.list.d/docker.list", repo.Path)
+	errorIfNotEqual(t, "/etc/apt/keyrings/docker.gpg", repo.KeyPath)
+
+	want := `deb [arch=amd64] https://download.example.org/linux/debian stretch stable edge
+deb-src [arch=amd64] https://download.example.org/linux/debian stretch stable edge
+`
+	errorIfNotEqual(t, want, string(repo.definition()))
+
+	// The signing key is trusted only for the source
+	repo.Key = "keys/docker.gpg"
+	want = `deb [arch=amd64 signed-by=/etc/apt/keyrings/docker.gpg] https://download.example.org/linux/debian stretch stable edge
+deb-src [arch=amd64 signed-by=/etc/apt/keyrings/docker.gpg] https://download.example.org/linux/debian stretch stable edge
+`
+	errorIfNotEqual(t, want, string(repo.definition()))
+	repo.Key = ""
+
+	if err := repo.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.KeyFingerprint = "9DC8 5822 9FC7 DD38 854A E2D8 8D81 803C 0EBF CD88"
+	if err := repo.Validate(); err == nil {
+		t.Error("expected validation error for 'key_fingerprint' without 'key'")
+	}
+}
+
+func TestPkgNGRepo(t *testing.T) {
+	L := newLuaState()
+	defer L.Close()
+
+	const code = `
+	myrepo = resource.pkgng_repo.new("myrepo")
+	myrepo.url = "pkg+http://pkg.example.org/${ABI}/latest"
+	myrepo.mirror_type = "srv"
+	myrepo.key = "pkg.example.org.pub"
+	`
+
+	if err := L.DoString(code); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := luaResource(L, "myrepo").(*PkgNGRepo)
+	errorIfNotEqual(t, "pkgng_repo", repo.Type)
+	errorIfNotEqual(t, "myrepo", repo.Name)
+	errorIfNotEqual(t, "/usr/local/etc/pkg/repos/myrepo.conf", repo.Path)
+	errorIfNotEqual(t, "/usr/local/etc/pkg/keys/myrepo.pub", repo.KeyPath)
+
+	want := `myrepo: {
+  url: "pkg+http://pkg.example.org/${ABI}/latest",
+  mirror_type: "srv",
+  signature_type: "pubkey",
+  pubkey: "/usr/local/etc/pkg/keys/myrepo.pub",
+  enabled: yes
+}
+`
+	errorIfNotEqual(t, want, string(repo.definition()))
+
+	repo.MirrorType = "ftp"
+	if err := repo.Validate(); err == nil {
+		t.Error("expected validation error for invalid mirror type")
+	}
+}
+
+func TestRepoKeyFingerprint(t *testing.T) {
+	siteRepo, err := ioutil.TempDir("", "gru-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(siteRepo)
+
+	key := []byte("-----BEGIN PUBLIC KEY-----\nMIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA\n-----END PUBLIC KEY-----\n")
+	if err := ioutil.WriteFile(filepath.Join(siteRepo, "local.pub"), key, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldSiteRepo := DefaultConfig.SiteRepo
+	DefaultConfig.SiteRepo = siteRepo
+	defer func() { DefaultConfig.SiteRepo = oldSiteRepo }()
+
+	r, err := NewPkgNGRepo("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := r.(*PkgNGRepo)
+	repo.URL = "pkg+http://pkg.example.org/${ABI}/latest"
+	repo.Key = "local.pub"
+	repo.KeyFingerprint = fmt.Sprintf("%X", sha256.Sum256(key))
+	if err := repo.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	errorIfNotEqual(t, key, repo.keyData)
+
+	repo.KeyFingerprint = "0123456789abcdef"
+	if err := repo.Initialize(); err == nil {
+		t.Error("expected error for mismatching key fingerprint")
+	}
+}
+
+func TestRepoFetchKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		fmt.Fprint(w, "key")
+	}))
+	defer server.Close()
+
+	key, err := fetchKey(context.Background(), server.URL+"/key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorIfNotEqual(t, []byte("key"), key)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := fetchKey(ctx, server.URL+"/slow"); err == nil {
+		t.Error("expected error when fetching the key is cancelled")
+	}
+}
+
+func TestRepoRemoveKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := NewYumRepo("myrepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var removed []byte
+	repo := r.(*YumRepo)
+	repo.Path = filepath.Join(dir, "myrepo.repo")
+	repo.KeyPath = filepath.Join(dir, "RPM-GPG-KEY-myrepo")
+	repo.removeKey = func(ctx context.Context, key []byte) error {
+		removed = key
+		return nil
+	}
+
+	if err := ioutil.WriteFile(repo.KeyPath, []byte("key"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Imported keys are removed even if the repository is
+	// rebuilt without it's key, e.g. when it is purged
+	if err := repo.uninstallKey(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	errorIfNotEqual(t, []byte("key"), removed)
+
+	if _, err := os.Stat(repo.KeyPath); !os.IsNotExist(err) {
+		t.Errorf("want %s to be removed", repo.KeyPath)
+	}
+}
+
+func TestRepoRefreshCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gru-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	yum, _ := NewYumRepo("myrepo")
+	apt, _ := NewAptSource("myrepo")
+	pkgng, _ := NewPkgNGRepo("myrepo")
+	repos := []*BaseRepo{
+		&yum.(*YumRepo).BaseRepo,
+		&apt.(*AptSource).BaseRepo,
+		&pkgng.(*PkgNGRepo).BaseRepo,
+	}
+
+	for _, repo := range repos {
+		repo.Path = filepath.Join(dir, "myrepo")
+		if err := ioutil.WriteFile(repo.Path, []byte("myrepo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// Defined repositories are refreshed on their own
+		if cmd := strings.Join(repo.refreshCommand(), " "); !strings.Contains(cmd, "myrepo") {
+			t.Errorf("want %s refresh command to refer to the repository, got %q", repo.Type, cmd)
+		}
+
+		// Removed repositories are no longer known to the package
+		// manager, so all repositories are refreshed instead
+		if err := os.Remove(repo.Path); err != nil {
+			t.Fatal(err)
+		}
+		if cmd := strings.Join(repo.refreshCommand(), " "); cmd == "" || strings.Contains(cmd, "myrepo") {
+			t.Errorf("want %s refresh command not to refer to the removed repository, got %q", repo.Type, cmd)
+		}
+	}
+}
+
+func TestDearmorKey(t *testing.T) {
+	binary := []byte{0x99, 0x01, 0x0d, 0x04, 0x5a, 0x2f, 0xae, 0xa2}
+	armored := fmt.Sprintf("-----BEGIN PGP PUBLIC KEY BLOCK-----\nVersion: GnuPG v2\n\n%s\n=abcd\n-----END PGP PUBLIC KEY BLOCK-----\n", base64.StdEncoding.EncodeToString(binary))
+
+	for _, key := range [][]byte{binary, []byte(armored)} {
+		got, err := dearmorKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		errorIfNotEqual(t, binary, got)
+	}
+
+	if _, err := dearmorKey([]byte("-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nmQEN\n")); err == nil {
+		t.Error("expected error for truncated armored key")
+	}
+}
+
+func TestParseGPGFingerprints(t *testing.T) {
+	out := `pub:-:4096:1:6A2FAEA2352C64E5:1387191234:::-:::scESC::::::23::0:
+fpr:::::::::91E97D7C4A5E96F17F3E888F6A2FAEA2352C64E5:
+uid:-::::1387191234::B8A3BBBA4EAF8D4A63E3E0F52E4E7B54FE5E6D37::Fedora EPEL (7) <epel@fedoraproject.org>::::::::::0:
+sub:-:4096:1:1234567890ABCDEF:1387191234::::::e::::::23:
+fpr:::::::::0000000000000000000000001234567890ABCDEF:
+`
+	errorIfNotEqual(t, []string{"91E97D7C4A5E96F17F3E888F6A2FAEA2352C64E5"}, parseGPGFingerprints([]byte(out)))
+	errorIfNotEqual(t, "91E97D7C4A5E96F17F3E888F6A2FAEA2352C64E5", normalizeFingerprint("91e9 7d7c 4a5e 96f1 7f3e  888f 6a2f aea2 352c 64e5"))
+}
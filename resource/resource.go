@@ -26,10 +26,12 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/dnaeon/gru/utils"
 	"github.com/yuin/gopher-lua"
@@ -91,6 +93,11 @@ type Resource interface {
 	// resource id for each dependency.
 	Dependencies() []string
 
+	// Dependents returns the list of resource ids, which
+	// depend on the current resource, i.e. resources which
+	// should be processed after the current resource.
+	Dependents() []string
+
 	// PresentStates returns the list of states, for which the
 	// resource is considered to be present
 	PresentStates() []string
@@ -121,6 +128,131 @@ type Resource interface {
 	// map are resource ids and their values are the functions to be
 	// executed if the resource state changes.
 	SubscribedTo() TriggerMap
+
+	// FailureTriggers returns a map of the resource ids and the
+	// functions to be executed if processing of the resource fails.
+	FailureTriggers() TriggerMap
+
+	// SuccessTriggers returns a map of the resource ids and the
+	// functions to be executed if the resource is processed
+	// successfully, regardless of whether it has changed or not.
+	SuccessTriggers() TriggerMap
+
+	// UnchangedTriggers returns a map of the resource ids and the
+	// functions to be executed if the resource is processed
+	// successfully and it's state has not changed.
+	UnchangedTriggers() TriggerMap
+
+	// ProcessingTimeout returns the maximum amount of time
+	// processing of the resource may take.
+	// A zero value means that there is no timeout.
+	ProcessingTimeout() time.Duration
+
+	// RetryPolicy returns the policy used for retrying failed
+	// operations on the resource.
+	RetryPolicy() RetryPolicy
+
+	// Notifies returns the list of resource ids, which are
+	// refreshed when the current resource has changed.
+	Notifies() []string
+
+	// TagList returns the list of tags for the resource,
+	// which are used for selecting resources to be processed.
+	TagList() []string
+
+	// Guards returns the only_if and unless guards of the resource,
+	// which are evaluated before processing the resource.
+	Guards() (onlyIf lua.LValue, unless lua.LValue)
+}
+
+// Refresher is an optional interface type implemented by resources,
+// which can be refreshed, e.g. a service which can be restarted.
+type Refresher interface {
+	// Refresh refreshes the resource
+	Refresh() error
+}
+
+// ContextRefresher is an optional interface type implemented by
+// refreshable resources, which support cancellation of the refresh.
+// When a resource implements this interface RefreshContext is used
+// instead of Refresh.
+type ContextRefresher interface {
+	// RefreshContext refreshes the resource using the given context
+	RefreshContext(ctx context.Context) error
+}
+
+// RetryPolicy type describes how failed operations on a
+// resource, e.g. evaluating or creating it, are retried.
+type RetryPolicy struct {
+	// Retries is the number of times a failed operation is retried
+	Retries int
+
+	// Delay is the time to wait before retrying a failed operation
+	Delay time.Duration
+
+	// MaxDelay is the maximum time to wait before retrying
+	// a failed operation. A zero value means no limit.
+	MaxDelay time.Duration
+
+	// Factor is the multiplying factor for the delay after
+	// each retry, e.g. a factor of 2 doubles the delay after
+	// each retry. A factor of 1 means a constant delay.
+	Factor float64
+}
+
+// ContextResource is an optional interface type implemented by
+// resources, which support cancellation of long running operations.
+// When a resource implements this interface the context-aware
+// methods are used instead of their Resource counterparts.
+type ContextResource interface {
+	// EvaluateContext evaluates the resource
+	EvaluateContext(ctx context.Context) (State, error)
+
+	// CreateContext creates the resource
+	CreateContext(ctx context.Context) error
+
+	// DeleteContext deletes the resource
+	DeleteContext(ctx context.Context) error
+}
+
+// ContextInitializer is an optional interface type implemented by
+// resources, which support cancellation of their initialization, e.g.
+// when establishing a connection to a remote API endpoint.
+type ContextInitializer interface {
+	// InitializeContext initializes the resource using the given context
+	InitializeContext(ctx context.Context) error
+}
+
+// Batcher is an optional interface type implemented by resources,
+// which can be processed together with other resources of the same
+// kind, e.g. packages installed in a single package manager transaction.
+// Resources with a timeout or retries are never processed in batches.
+type Batcher interface {
+	// BatchKey returns the key used for grouping resources. Resources
+	// with the same key can be processed in the same batch.
+	BatchKey() string
+
+	// NewBatch creates a batch for processing the given resources,
+	// which have the same batch key as the current resource.
+	NewBatch(resources []Resource) Batch
+}
+
+// Batch type processes a group of resources using a single operation
+// for evaluating, creating and deleting the resources.
+type Batch interface {
+	// Load evaluates all resources in the batch in a single operation.
+	// Until the batch is closed evaluating a resource from the batch
+	// uses the result of the last load.
+	Load(ctx context.Context) error
+
+	// Create creates the given resources from the batch
+	Create(ctx context.Context, resources []Resource) error
+
+	// Delete deletes the given resources from the batch
+	Delete(ctx context.Context, resources []Resource) error
+
+	// Close discards the result of the last load
+	Close()
 }
 
 // Config type contains various settings used by the resources
@@ -182,6 +314,89 @@ type Base struct {
 	// current resource to the one that is being monitored, so that the
 	// monitored resource is evaluated and processed first.
 	Subscribe map[string]*lua.LFunction `luar:"subscribe"`
+
+	// OnFailure is a map whose keys are resource ids that the
+	// current resource monitors for failures and the values are
+	// functions that will be executed if processing of the
+	// monitored resource has failed. Like with subscriptions, the
+	// monitored resource is evaluated and processed first.
+	OnFailure map[string]*lua.LFunction `luar:"on_failure"`
+
+	// OnSuccess is a map whose keys are resource ids that the
+	// current resource monitors and the values are functions
+	// that will be executed if the monitored resource has been
+	// processed successfully.
+	OnSuccess map[string]*lua.LFunction `luar:"on_success"`
+
+	// OnUnchanged is a map whose keys are resource ids that the
+	// current resource monitors and the values are functions
+	// that will be executed if the monitored resource has been
+	// processed successfully and it's state has not changed.
+	OnUnchanged map[string]*lua.LFunction `luar:"on_unchanged"`
+
+	// Notify contains the resource ids of resources, which are
+	// refreshed if the current resource has changed. The notified
+	// resources must implement the Refresher interface.
+	// Notifying other resources also automatically creates an edge
+	// in the dependency graph pointing from the notified resource
+	// to the current one, so that the current resource is evaluated
+	// and processed first.
+	Notify []string `luar:"notify"`
+
+	// Before contains the resource ids of resources, which should
+	// be processed after the current resource. This allows for
+	// ordering resources, which are declared elsewhere, e.g.
+	// in another module, which cannot be modified.
+	Before []string `luar:"before"`
+
+	// RequiredBy contains the resource ids of resources, which
+	// require the current resource. It has the same effect as
+	// adding the current resource to the required resources of
+	// the resources listed here.
+	RequiredBy []string `luar:"required_by"`
+
+	// Tags contains the tags for the resource, which
+	// can be used to process only a subset of the resources
+	// from a catalog, e.g. only the configuration files.
+	Tags []string `luar:"tags"`
+
+	// OnlyIf is a guard, which is evaluated prior processing the
+	// resource. The guard can be either a Lua function or a shell
+	// command. If the function returns false or the command exits
+	// with a non-zero status, the resource is skipped.
+	OnlyIf lua.LValue `luar:"only_if"`
+
+	// Unless is a guard, which is evaluated prior processing the
+	// resource. The guard can be either a Lua function or a shell
+	// command. If the function returns true or the command exits
+	// with a zero status, the resource is skipped.
+	Unless lua.LValue `luar:"unless"`
+
+	// Timeout is the maximum number of seconds processing of the
+	// resource may take, before it is considered as failed. Only
+	// resources implementing the context-aware interfaces can be
+	// interrupted once an operation on them has been started.
+	// Defaults to zero, which means no timeout.
+	Timeout int `luar:"timeout"`
+
+	// Retries is the number of times failed operations on the
+	// resource are retried, before the resource is considered
+	// as failed. Defaults to zero, which means no retries.
+	Retries int `luar:"retries"`
+
+	// RetryDelay is the number of seconds to wait before retrying
+	// a failed operation. Defaults to zero.
+	RetryDelay int `luar:"retry_delay"`
+
+	// RetryMaxDelay is the maximum number of seconds to wait before
+	// retrying a failed operation when using exponential backoff.
+	// Defaults to zero, which means no limit.
+	RetryMaxDelay int `luar:"retry_max_delay"`
+
+	// RetryBackoff is the factor used for exponential backoff
+	// between retries, e.g. a factor of 2 doubles the delay after
+	// each retry. Defaults to zero, which means a constant delay.
+	RetryBackoff float64 `luar:"retry_backoff"`
 }
 
 // ID returns the unique resource id
@@ -210,6 +425,20 @@ func (b *Base) Validate() error {
 		return ErrInvalidName
 	}
 
+	guards := map[string]lua.LValue{
+		"only_if": b.OnlyIf,
+		"unless":  b.Unless,
+	}
+
+	for name, guard := range guards {
+		switch guard.(type) {
+		case nil, *lua.LNilType, *lua.LFunction, lua.LString:
+			continue
+		default:
+			return fmt.Errorf("Invalid %s guard, must be a function or a string", name)
+		}
+	}
+
 	states := append(b.PresentStatesList, b.AbsentStatesList...)
 	if !utils.NewList(states...).Contains(b.State) {
 		return fmt.Errorf("Invalid state '%s'", b.State)
@@ -218,11 +447,29 @@ func (b *Base) Validate() error {
 	return nil
 }
 
+// wantAbsent returns true if the resource should be absent.
+// Resources, which should be absent, are usually rebuilt only
+// from their type and name, e.g. when purging them, so they
+// should not require any other fields to be set.
+func (b *Base) wantAbsent() bool {
+	return utils.NewList(b.AbsentStatesList...).Contains(b.State)
+}
+
 // Dependencies returns the list of resource dependencies.
 func (b *Base) Dependencies() []string {
 	return b.Require
 }
 
+// Dependents returns the list of resources,
+// which depend on the resource.
+func (b *Base) Dependents() []string {
+	dependents := make([]string, 0, len(b.Before)+len(b.RequiredBy))
+	dependents = append(dependents, b.Before...)
+	dependents = append(dependents, b.RequiredBy...)
+
+	return dependents
+}
+
 // PresentStates returns the list of states, for which the
 // resource is considered to be present
 func (b *Base) PresentStates() []string {
@@ -248,7 +495,64 @@ func (b *Base) SubscribedTo() TriggerMap {
 	return b.Subscribe
 }
 
+// FailureTriggers returns a map of resources for which the
+// resource monitors for failures.
+func (b *Base) FailureTriggers() TriggerMap {
+	return b.OnFailure
+}
+
+// SuccessTriggers returns a map of resources for which the
+// resource monitors for successful processing.
+func (b *Base) SuccessTriggers() TriggerMap {
+	return b.OnSuccess
+}
+
+// UnchangedTriggers returns a map of resources for which the
+// resource monitors for being processed without changes.
+func (b *Base) UnchangedTriggers() TriggerMap {
+	return b.OnUnchanged
+}
+
 // Properties returns the list of properties for the resource.
 func (b *Base) Properties() []Property {
 	return b.PropertyList
 }
+
+// Notifies returns the list of resources, which are
+// refreshed when the resource has changed.
+func (b *Base) Notifies() []string {
+	return b.Notify
+}
+
+// Guards returns the only_if and unless guards of the resource.
+func (b *Base) Guards() (lua.LValue, lua.LValue) {
+	return b.OnlyIf, b.Unless
+}
+
+// TagList returns the list of tags for the resource.
+func (b *Base) TagList() []string {
+	return b.Tags
+}
+
+// ProcessingTimeout returns the maximum amount of time
+// processing of the resource may take.
+func (b *Base) ProcessingTimeout() time.Duration {
+	return time.Duration(b.Timeout) * time.Second
+}
+
+// RetryPolicy returns the policy used for retrying failed
+// operations on the resource.
+func (b *Base) RetryPolicy() RetryPolicy {
+	policy := RetryPolicy{
+		Retries:  b.Retries,
+		Delay:    time.Duration(b.RetryDelay) * time.Second,
+		MaxDelay: time.Duration(b.RetryMaxDelay) * time.Second,
+		Factor:   b.RetryBackoff,
+	}
+
+	if policy.Factor < 1 {
+		policy.Factor = 1
+	}
+
+	return policy
+}
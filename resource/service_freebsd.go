@@ -46,6 +46,10 @@ type Service struct {
 	// If true then enable the service during boot-time
 	Enable bool `luar:"enable"`
 
+	// If true then reload the service instead of
+	// restarting it, when the service is refreshed
+	Reload bool `luar:"reload"`
+
 	// RCVar (see rc.subr(8)), set to {svcname}_enable by default.
 	// If service doesn't define rcvar, you should set svc.rcvar = "".
 	RCVar string `luar:"rcvar"`
@@ -64,8 +68,12 @@ func NewService(name string) (Resource, error) {
 			AbsentStatesList:  []string{"absent", "stopped"},
 			Concurrent:        false,
 			Subscribe:         make(TriggerMap),
+			OnFailure:         make(TriggerMap),
+			OnSuccess:         make(TriggerMap),
+			OnUnchanged:       make(TriggerMap),
 		},
 		Enable: true,
+		Reload: false,
 		RCVar:  fmt.Sprintf("%v_enable", name),
 	}
 
@@ -75,6 +83,7 @@ func NewService(name string) (Resource, error) {
 			PropertyName:         "enable",
 			PropertySetFunc:      s.setEnable,
 			PropertyIsSyncedFunc: s.isEnableSynced,
+			PropertyValuesFunc:   s.enableValues,
 		},
 	}
 
@@ -113,19 +122,33 @@ func (s *Service) Delete() error {
 	return exec.Command("service", s.Name, "onestop").Run()
 }
 
-// isEnableSynced checks whether the service is in the desired state.
-func (s *Service) isEnableSynced() (bool, error) {
-	var enabled bool
+// Refresh restarts or reloads the service.
+func (s *Service) Refresh() error {
+	if s.Reload {
+		Logf("%s reloading service\n", s.ID())
+		return exec.Command("service", s.Name, "onereload").Run()
+	}
+
+	Logf("%s restarting service\n", s.ID())
+
+	return exec.Command("service", s.Name, "onerestart").Run()
+}
 
+// isEnabled checks whether the service is enabled during boot-time.
+func (s *Service) isEnabled() bool {
 	err := exec.Command("service", s.Name, "enabled").Run()
-	switch err {
-	case nil:
-		enabled = true
-	default:
-		enabled = false
-	}
 
-	return enabled == s.Enable, nil
+	return err == nil
+}
+
+// isEnableSynced checks whether the service is in the desired state.
+func (s *Service) isEnableSynced() (bool, error) {
+	return s.isEnabled() == s.Enable, nil
+}
+
+// enableValues returns the current and desired values of the property.
+func (s *Service) enableValues() (interface{}, interface{}, error) {
+	return s.isEnabled(), s.Enable, nil
 }
 
 // setEnable enables or disables the service during boot-time.
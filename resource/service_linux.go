@@ -28,6 +28,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -53,6 +54,10 @@ type Service struct {
 	// service during boot-time. Defaults to true.
 	Enable bool `luar:"enable"`
 
+	// Reload specifies whether to reload the service instead of
+	// restarting it, when the service is refreshed. Defaults to false.
+	Reload bool `luar:"reload"`
+
 	// Systemd unit name
 	unit string `luar:"-"`
 
@@ -76,8 +81,12 @@ func NewService(name string) (Resource, error) {
 			AbsentStatesList:  []string{"absent", "stopped"},
 			Concurrent:        true,
 			Subscribe:         make(TriggerMap),
+			OnFailure:         make(TriggerMap),
+			OnSuccess:         make(TriggerMap),
+			OnUnchanged:       make(TriggerMap),
 		},
 		Enable: true,
+		Reload: false,
 		unit:   fmt.Sprintf("%s.service", name),
 	}
 
@@ -87,6 +96,7 @@ func NewService(name string) (Resource, error) {
 			PropertyName:         "enable",
 			PropertySetFunc:      s.setEnable,
 			PropertyIsSyncedFunc: s.isEnableSynced,
+			PropertyValuesFunc:   s.enableValues,
 		},
 	}
 
@@ -104,6 +114,11 @@ func (s *Service) Initialize() error {
 
 // Evaluate evaluates the state of the resource
 func (s *Service) Evaluate() (State, error) {
+	return s.EvaluateContext(context.Background())
+}
+
+// EvaluateContext evaluates the state of the resource
+func (s *Service) EvaluateContext(ctx context.Context) (State, error) {
 	state := State{
 		Current: "unknown",
 		Want:    s.State,
@@ -130,32 +145,78 @@ func (s *Service) Evaluate() (State, error) {
 
 // Create starts the service.
 func (s *Service) Create() error {
+	return s.CreateContext(context.Background())
+}
+
+// CreateContext starts the service using the given context.
+func (s *Service) CreateContext(ctx context.Context) error {
 	Logf("%s starting service\n", s.ID())
 
-	ch := make(chan string)
+	// Buffered, so that systemd can deliver the job result
+	// even if we are no longer waiting for it
+	ch := make(chan string, 1)
 	jobID, err := s.conn.StartUnit(s.unit, "replace", ch)
 	if err != nil {
 		return err
 	}
 
-	result := <-ch
-	Logf("%s systemd job id %d result: %s\n", s.ID(), jobID, result)
-
-	return nil
+	return s.waitJob(ctx, jobID, ch)
 }
 
 // Delete stops the service.
 func (s *Service) Delete() error {
+	return s.DeleteContext(context.Background())
+}
+
+// DeleteContext stops the service using the given context.
+func (s *Service) DeleteContext(ctx context.Context) error {
 	Logf("%s stopping service\n", s.ID())
 
-	ch := make(chan string)
+	ch := make(chan string, 1)
 	jobID, err := s.conn.StopUnit(s.unit, "replace", ch)
 	if err != nil {
 		return err
 	}
 
-	result := <-ch
-	Logf("%s systemd job id %d result: %s\n", s.ID(), jobID, result)
+	return s.waitJob(ctx, jobID, ch)
+}
+
+// Refresh restarts or reloads the service.
+func (s *Service) Refresh() error {
+	return s.RefreshContext(context.Background())
+}
+
+// RefreshContext restarts or reloads the service using the given context.
+func (s *Service) RefreshContext(ctx context.Context) error {
+	ch := make(chan string, 1)
+
+	var jobID int
+	var err error
+	if s.Reload {
+		Logf("%s reloading service\n", s.ID())
+		jobID, err = s.conn.ReloadUnit(s.unit, "replace", ch)
+	} else {
+		Logf("%s restarting service\n", s.ID())
+		jobID, err = s.conn.RestartUnit(s.unit, "replace", ch)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return s.waitJob(ctx, jobID, ch)
+}
+
+// waitJob waits for a systemd job to complete or
+// for the context to be done, whichever happens first.
+func (s *Service) waitJob(ctx context.Context, jobID int, ch <-chan string) error {
+	select {
+	case result := <-ch:
+		Logf("%s systemd job id %d result: %s\n", s.ID(), jobID, result)
+	case <-ctx.Done():
+		Logf("%s systemd job id %d did not complete: %s\n", s.ID(), jobID, ctx.Err())
+		return ctx.Err()
+	}
 
 	return nil
 }
@@ -201,29 +262,46 @@ func (s *Service) disableUnit() error {
 	return nil
 }
 
-// isEnableSynced determines whether the property is synced.
-func (s *Service) isEnableSynced() (bool, error) {
+// isEnabled determines whether the service unit is enabled during boot-time.
+func (s *Service) isEnabled() (bool, error) {
 	unitState, err := s.conn.GetUnitProperty(s.unit, "UnitFileState")
 	if err != nil {
 		return false, err
 	}
 
-	var enabled bool
 	value := unitState.Value.Value().(string)
 	switch value {
 	case "enabled", "static", "enabled-runtime", "linked", "linked-runtime":
-		enabled = true
+		return true, nil
 	case "disabled", "masked", "masked-runtime":
-		enabled = false
+		return false, nil
 	case "invalid":
 		fallthrough
 	default:
 		return false, errors.New("Invalid unit state")
 	}
+}
+
+// isEnableSynced determines whether the property is synced.
+func (s *Service) isEnableSynced() (bool, error) {
+	enabled, err := s.isEnabled()
+	if err != nil {
+		return false, err
+	}
 
 	return s.Enable == enabled, nil
 }
 
+// enableValues returns the current and desired values of the property.
+func (s *Service) enableValues() (interface{}, interface{}, error) {
+	enabled, err := s.isEnabled()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return enabled, s.Enable, nil
+}
+
 // setEnable sets the property to it's desired state.
 func (s *Service) setEnable() error {
 	var action func() error
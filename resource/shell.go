@@ -26,6 +26,7 @@
 package resource
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"strings"
@@ -74,6 +75,9 @@ func NewShell(name string) (Resource, error) {
 			AbsentStatesList:  []string{"absent"},
 			Concurrent:        true,
 			Subscribe:         make(TriggerMap),
+			OnFailure:         make(TriggerMap),
+			OnSuccess:         make(TriggerMap),
+			OnUnchanged:       make(TriggerMap),
 		},
 		Command: name,
 		Creates: "",
@@ -85,6 +89,11 @@ func NewShell(name string) (Resource, error) {
 
 // Evaluate evaluates the state of the resource
 func (s *Shell) Evaluate() (State, error) {
+	return s.EvaluateContext(context.Background())
+}
+
+// EvaluateContext evaluates the state of the resource
+func (s *Shell) EvaluateContext(ctx context.Context) (State, error) {
 	// Assumes that the command to be executed is idempotent
 	//
 	// Sets the current state to absent and wanted to be present,
@@ -112,10 +121,15 @@ func (s *Shell) Evaluate() (State, error) {
 
 // Create executes the shell command
 func (s *Shell) Create() error {
+	return s.CreateContext(context.Background())
+}
+
+// CreateContext executes the shell command using the given context
+func (s *Shell) CreateContext(ctx context.Context) error {
 	Logf("%s executing command\n", s.ID())
 
 	args := strings.Fields(s.Command)
-	cmd := exec.Command(args[0], args[1:]...)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
 	out, err := cmd.CombinedOutput()
 
 	if !s.Mute {
@@ -132,6 +146,21 @@ func (s *Shell) Delete() error {
 	return nil
 }
 
+// DeleteContext is a no-op
+func (s *Shell) DeleteContext(ctx context.Context) error {
+	return nil
+}
+
+// Refresh executes the shell command again
+func (s *Shell) Refresh() error {
+	return s.RefreshContext(context.Background())
+}
+
+// RefreshContext executes the shell command again using the given context
+func (s *Shell) RefreshContext(ctx context.Context) error {
+	return s.CreateContext(ctx)
+}
+
 // Update is a no-op
 func (s *Shell) Update() error {
 	return nil
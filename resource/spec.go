@@ -0,0 +1,338 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// Spec type represents a resource in a serialized form, which
+// can be used for rebuilding the resource without using Lua.
+type Spec struct {
+	// Type of the resource
+	Type string `json:"type"`
+
+	// Name of the resource
+	Name string `json:"name"`
+
+	// Provider is the type the provider of the resource is registered
+	// for, if it is not the type of the resource, e.g. the yum provider
+	// of package resources
+	Provider string `json:"provider,omitempty"`
+
+	// Fields contains the values of the resource fields, which
+	// are exposed to Lua. The keys are the field names as seen
+	// from Lua.
+	Fields map[string]json.RawMessage `json:"fields"`
+}
+
+// Resolver is an optional interface type implemented by resources,
+// whose desired state depends on data outside of the resource, e.g.
+// a source file in the site repo. Resolve loads the data into the
+// resource, so that its serialized form captures the exact desired
+// state of the resource.
+type Resolver interface {
+	// Resolve loads the external data into the resource
+	Resolve() error
+}
+
+// Provided is an optional interface type implemented by resources,
+// whose provider is registered for a different type than the type
+// of the resource, e.g. package resources created by the yum provider.
+type Provided interface {
+	// Provider returns the type the provider of the resource is registered for
+	Provider() string
+}
+
+// NewSpec creates the serialized form of a resource.
+// Resources with Lua triggers, with Lua functions used as
+// guards or with secrets cannot be serialized and an error
+// is returned for them. External data used by the resource
+// is resolved and serialized along with the resource.
+func NewSpec(r Resource) (*Spec, error) {
+	return newSpec(r, false)
+}
+
+// DescribeResource creates the serialized form of a resource, which
+// is used for describing the resource, e.g. when comparing resources.
+// Lua triggers are represented by the ids of the monitored resources
+// and Lua functions used as guards are represented by a placeholder.
+// External data is resolved and binary content, e.g. the content of
+// files, is represented by it's checksum. All of them are ignored
+// when rebuilding the resource from the spec.
+func DescribeResource(r Resource) (*Spec, error) {
+	return newSpec(r, true)
+}
+
+// luaFunctionPlaceholder is used for describing Lua functions
+const luaFunctionPlaceholder = "<lua function>"
+
+// secretPlaceholder is used for describing secrets
+const secretPlaceholder = "<secret>"
+
+// checksumPrefix is used for describing binary content by it's checksum
+const checksumPrefix = "sha256:"
+
+// newSpec creates the serialized form of a resource. If describe is
+// true, Lua functions are described instead of returning an error.
+func newSpec(r Resource, describe bool) (*Spec, error) {
+	v := reflect.Indirect(reflect.ValueOf(r))
+	base, ok := findBase(v)
+	if !ok {
+		return nil, fmt.Errorf("%s does not embed the base resource", r.ID())
+	}
+
+	if resolver, ok := r.(Resolver); ok {
+		if err := resolver.Resolve(); err != nil {
+			return nil, fmt.Errorf("%s: %s", r.ID(), err)
+		}
+	}
+
+	spec := &Spec{
+		Type:   base.Type,
+		Name:   base.Name,
+		Fields: make(map[string]json.RawMessage),
+	}
+
+	if provided, ok := r.(Provided); ok && provided.Provider() != base.Type {
+		spec.Provider = provided.Provider()
+	}
+
+	err := walkFields(v, func(name string, f reflect.StructField, field reflect.Value) error {
+		// Secrets are never serialized, only their presence is described
+		if isSecret(f) {
+			if isZero(field) {
+				return nil
+			}
+			if describe {
+				return spec.setField(name, secretPlaceholder)
+			}
+			return fmt.Errorf("%s has a secret in field %s, which cannot be serialized", r.ID(), name)
+		}
+
+		switch value := field.Interface().(type) {
+		case []byte:
+			if describe && value != nil {
+				return spec.setField(name, fmt.Sprintf("%s%x", checksumPrefix, sha256.Sum256(value)))
+			}
+		case TriggerMap:
+			return spec.setTriggers(r, name, value, describe)
+		case map[string]*lua.LFunction:
+			return spec.setTriggers(r, name, value, describe)
+		case lua.LValue:
+			switch guard := value.(type) {
+			case nil, *lua.LNilType:
+				return nil
+			case lua.LString:
+				return spec.setField(name, string(guard))
+			default:
+				if describe {
+					return spec.setField(name, luaFunctionPlaceholder)
+				}
+				return fmt.Errorf("%s has a Lua %s in field %s, which cannot be serialized", r.ID(), guard.Type(), name)
+			}
+		}
+
+		if field.Kind() == reflect.Interface && field.IsNil() {
+			return nil
+		}
+
+		return spec.setField(name, field.Interface())
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// ID returns the unique identifier of the serialized resource.
+func (s *Spec) ID() string {
+	return fmt.Sprintf("%s[%s]", s.Type, s.Name)
+}
+
+// setTriggers stores the ids of the resources monitored by Lua triggers.
+func (s *Spec) setTriggers(r Resource, name string, triggers TriggerMap, describe bool) error {
+	if len(triggers) == 0 {
+		return nil
+	}
+
+	if !describe {
+		return fmt.Errorf("%s has Lua triggers, which cannot be serialized", r.ID())
+	}
+
+	subscribed := make([]string, 0, len(triggers))
+	for id := range triggers {
+		subscribed = append(subscribed, id)
+	}
+	sort.Strings(subscribed)
+
+	return s.setField(name, subscribed)
+}
+
+// setField stores the serialized value of a field
+func (s *Spec) setField(name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("unable to serialize field %s: %s", name, err)
+	}
+	s.Fields[name] = data
+
+	return nil
+}
+
+// Resource rebuilds the resource from it's serialized form by
+// using the provider of the resource, if it is known, or the
+// provider registered for the resource type otherwise.
+func (s *Spec) Resource() (Resource, error) {
+	typ := s.Type
+	if s.Provider != "" {
+		typ = s.Provider
+	}
+
+	r, err := NewResource(typ, s.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]reflect.Value)
+	secrets := make(map[string]bool)
+	walkFields(reflect.Indirect(reflect.ValueOf(r)), func(name string, f reflect.StructField, field reflect.Value) error {
+		fields[name] = field
+		secrets[name] = isSecret(f)
+		return nil
+	})
+
+	for name, data := range s.Fields {
+		field, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("%s has no field %s", r.ID(), name)
+		}
+
+		// Secrets are never serialized and cannot be rebuilt
+		if secrets[name] {
+			continue
+		}
+
+		// Described binary content cannot be rebuilt
+		if field.Type() == reflect.TypeOf([]byte{}) && strings.HasPrefix(string(data), `"`+checksumPrefix) {
+			continue
+		}
+
+		// Lua triggers cannot be rebuilt
+		if field.Type() == reflect.TypeOf(TriggerMap{}) || field.Type() == reflect.TypeOf(map[string]*lua.LFunction{}) {
+			continue
+		}
+
+		// Guards can be rebuilt only from shell commands
+		if field.Type() == reflect.TypeOf((*lua.LValue)(nil)).Elem() {
+			var command string
+			if err := json.Unmarshal(data, &command); err != nil {
+				return nil, fmt.Errorf("%s: unable to deserialize field %s: %s", r.ID(), name, err)
+			}
+			if command != luaFunctionPlaceholder {
+				field.Set(reflect.ValueOf(lua.LString(command)))
+			}
+			continue
+		}
+
+		if err := json.Unmarshal(data, field.Addr().Interface()); err != nil {
+			return nil, fmt.Errorf("%s: unable to deserialize field %s: %s", r.ID(), name, err)
+		}
+	}
+
+	return r, nil
+}
+
+// walkFields calls fn for each field of a resource, which is
+// exposed to Lua, including the fields of embedded types.
+func walkFields(v reflect.Value, fn func(name string, f reflect.StructField, field reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := walkFields(v.Field(i), fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Skip unexported fields and fields hidden from Lua
+		tag := f.Tag.Get("luar")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = f.Name
+		}
+
+		if err := fn(name, f, v.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isSecret returns true if the field holds a secret, e.g. a password.
+// Secret fields are tagged with `spec:"secret"`.
+func isSecret(f reflect.StructField) bool {
+	return f.Tag.Get("spec") == "secret"
+}
+
+// isZero returns true if the value is the zero value of its type
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// findBase finds the base resource embedded in a resource.
+func findBase(v reflect.Value) (*Base, bool) {
+	if base, ok := v.Addr().Interface().(*Base); ok {
+		return base, true
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if base, ok := findBase(v.Field(i)); ok {
+				return base, true
+			}
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2015-2017 Marin Atanasov Nikolov <dnaeon@gmail.com>
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions
+// are met:
+//
+//  1. Redistributions of source code must retain the above copyright
+//     notice, this list of conditions and the following disclaimer
+//     in this position and unchanged.
+//  2. Redistributions in binary form must reproduce the above copyright
+//     notice, this list of conditions and the following disclaimer in the
+//     documentation and/or other materials provided with the distribution.
+//
+// THIS SOFTWARE IS PROVIDED BY THE AUTHOR(S) ``AS IS'' AND ANY EXPRESS OR
+// IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES
+// OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+// IN NO EVENT SHALL THE AUTHOR(S) BE LIABLE FOR ANY DIRECT, INDIRECT,
+// INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT
+// NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+// THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// credentials is a resource used for testing the handling of secrets
+type credentials struct {
+	Base
+
+	Username string `luar:"username" spec:"secret"`
+	Password string `luar:"password" spec:"secret"`
+	Endpoint string `luar:"endpoint"`
+}
+
+func (c *credentials) Evaluate() (State, error) { return State{}, nil }
+func (c *credentials) Create() error            { return nil }
+func (c *credentials) Delete() error            { return nil }
+
+func newCredentials() *credentials {
+	return &credentials{
+		Base: Base{
+			Name:              "foo",
+			Type:              "credentials",
+			State:             "present",
+			Require:           make([]string, 0),
+			PresentStatesList: []string{"present"},
+			AbsentStatesList:  []string{"absent"},
+		},
+	}
+}
+
+func TestSpecSecrets(t *testing.T) {
+	// Resources without secrets can be compiled
+	r := newCredentials()
+	r.Endpoint = ""
+	spec, err := NewSpec(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Fields["password"]; ok {
+		t.Errorf("want empty secret not to be serialized")
+	}
+
+	r.Endpoint = "https://vc01.example.org/sdk"
+	r.Username = "root"
+	r.Password = "myp4ssw0rd"
+	if _, err := NewSpec(r); err == nil {
+		t.Errorf("want error when compiling a resource with secrets")
+	}
+
+	spec, err = DescribeResource(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"username", "password"} {
+		var value string
+		if err := json.Unmarshal(spec.Fields[name], &value); err != nil {
+			t.Fatal(err)
+		}
+		errorIfNotEqual(t, secretPlaceholder, value)
+	}
+
+	// Described secrets are not restored when rebuilding the resource
+	RegisterProvider(ProviderItem{
+		Type:      "credentials",
+		Provider:  func(name string) (Resource, error) { return newCredentials(), nil },
+		Namespace: DefaultResourceNamespace,
+	})
+
+	rebuilt, err := spec.Resource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorIfNotEqual(t, "", rebuilt.(*credentials).Password)
+	errorIfNotEqual(t, r.Endpoint, rebuilt.(*credentials).Endpoint)
+}
+
+func TestSpecDescribeContent(t *testing.T) {
+	siteRepo, err := ioutil.TempDir("", "gru-spec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(siteRepo)
+
+	oldSiteRepo := DefaultConfig.SiteRepo
+	DefaultConfig.SiteRepo = siteRepo
+	defer func() { DefaultConfig.SiteRepo = oldSiteRepo }()
+
+	describe := func(data string) map[string]string {
+		if err := ioutil.WriteFile(filepath.Join(siteRepo, "foo.txt"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := NewFile("/tmp/foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.(*File).Source = "foo.txt"
+
+		spec, err := DescribeResource(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Described content is ignored when rebuilding the resource
+		if _, err := spec.Resource(); err != nil {
+			t.Fatal(err)
+		}
+
+		fields := make(map[string]string)
+		for _, name := range []string{"source", "content"} {
+			var value string
+			if err := json.Unmarshal(spec.Fields[name], &value); err != nil {
+				t.Fatal(err)
+			}
+			fields[name] = value
+		}
+
+		return fields
+	}
+
+	// Changes to the source file are described by the content checksum
+	for _, data := range []string{"foo", "bar"} {
+		want := map[string]string{
+			"source":  "",
+			"content": fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(data))),
+		}
+		errorIfNotEqual(t, want, describe(data))
+	}
+}
@@ -57,6 +57,9 @@ func NewSysRC(name string) (Resource, error) {
 			AbsentStatesList:  []string{"absent"},
 			Concurrent:        false,
 			Subscribe:         make(TriggerMap),
+			OnFailure:         make(TriggerMap),
+			OnSuccess:         make(TriggerMap),
+			OnUnchanged:       make(TriggerMap),
 		},
 	}
 
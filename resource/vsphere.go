@@ -61,11 +61,11 @@ type BaseVSphere struct {
 
 	// Username to use when connecting to the vSphere endpoint.
 	// Defaults to an empty string.
-	Username string `luar:"username"`
+	Username string `luar:"username" spec:"secret"`
 
 	// Password to use when connecting to the vSphere endpoint.
 	// Defaults to an empty string.
-	Password string `luar:"password"`
+	Password string `luar:"password" spec:"secret"`
 
 	// Endpoint to the VMware vSphere API. Defaults to an empty string.
 	Endpoint string `luar:"endpoint"`
@@ -121,7 +121,14 @@ func (bv *BaseVSphere) Validate() error {
 
 // Initialize establishes a connection to the remote vSphere API endpoint.
 func (bv *BaseVSphere) Initialize() error {
-	bv.ctx, bv.cancel = context.WithCancel(context.Background())
+	return bv.InitializeContext(context.Background())
+}
+
+// InitializeContext establishes a connection to the remote vSphere API
+// endpoint using the given context. The context is also used for any
+// calls to the API, which are not given a context of their own.
+func (bv *BaseVSphere) InitializeContext(ctx context.Context) error {
+	bv.ctx, bv.cancel = context.WithCancel(ctx)
 
 	// Connect and login to the VMWare vSphere API endpoint
 	c, err := govmomi.NewClient(bv.ctx, bv.url, bv.Insecure)
@@ -26,6 +26,7 @@
 package resource
 
 import (
+	"context"
 	"path"
 
 	"github.com/vmware/govmomi/find"
@@ -114,6 +115,12 @@ func (c *Cluster) isClusterConfigSynced() (bool, error) {
 
 // setClusterConfig sets the cluster configuration to the desired state.
 func (c *Cluster) setClusterConfig() error {
+	return c.setClusterConfigContext(c.ctx)
+}
+
+// setClusterConfigContext sets the cluster configuration to the desired
+// state using the given context.
+func (c *Cluster) setClusterConfigContext(ctx context.Context) error {
 	Logf("%s setting cluster config\n", c.ID())
 
 	spec := types.ClusterConfigSpec{
@@ -126,17 +133,17 @@ func (c *Cluster) setClusterConfig() error {
 		},
 	}
 
-	obj, err := c.finder.ClusterComputeResource(c.ctx, path.Join(c.Path, c.Name))
+	obj, err := c.finder.ClusterComputeResource(ctx, path.Join(c.Path, c.Name))
 	if err != nil {
 		return err
 	}
 
-	task, err := obj.ReconfigureCluster(c.ctx, spec)
+	task, err := obj.ReconfigureCluster(ctx, spec)
 	if err != nil {
 		return err
 	}
 
-	return task.Wait(c.ctx)
+	return task.Wait(ctx)
 }
 
 // NewCluster creates a new resource for managing clusters in a
@@ -153,6 +160,9 @@ func NewCluster(name string) (Resource, error) {
 				AbsentStatesList:  []string{"absent"},
 				Concurrent:        true,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
 			Username: "",
 			Password: "",
@@ -166,9 +176,10 @@ func NewCluster(name string) (Resource, error) {
 	// Set resource properties
 	c.PropertyList = []Property{
 		&ResourceProperty{
-			PropertyName:         "cluster-config",
-			PropertySetFunc:      c.setClusterConfig,
-			PropertyIsSyncedFunc: c.isClusterConfigSynced,
+			PropertyName:           "cluster-config",
+			PropertySetFunc:        c.setClusterConfig,
+			PropertySetContextFunc: c.setClusterConfigContext,
+			PropertyIsSyncedFunc:   c.isClusterConfigSynced,
 		},
 	}
 
@@ -177,12 +188,17 @@ func NewCluster(name string) (Resource, error) {
 
 // Evaluate evalutes the state of the cluster.
 func (c *Cluster) Evaluate() (State, error) {
+	return c.EvaluateContext(c.ctx)
+}
+
+// EvaluateContext evaluates the state of the cluster using the given context.
+func (c *Cluster) EvaluateContext(ctx context.Context) (State, error) {
 	state := State{
 		Current: "unknown",
 		Want:    c.State,
 	}
 
-	_, err := c.finder.ClusterComputeResource(c.ctx, path.Join(c.Path, c.Name))
+	_, err := c.finder.ClusterComputeResource(ctx, path.Join(c.Path, c.Name))
 	if err != nil {
 		// Cluster is absent
 		if _, ok := err.(*find.NotFoundError); ok {
@@ -201,31 +217,41 @@ func (c *Cluster) Evaluate() (State, error) {
 
 // Create creates a new cluster.
 func (c *Cluster) Create() error {
+	return c.CreateContext(c.ctx)
+}
+
+// CreateContext creates a new cluster using the given context.
+func (c *Cluster) CreateContext(ctx context.Context) error {
 	Logf("%s creating cluster\n", c.ID())
 
-	folder, err := c.finder.Folder(c.ctx, c.Path)
+	folder, err := c.finder.Folder(ctx, c.Path)
 	if err != nil {
 		return err
 	}
 
-	_, err = folder.CreateCluster(c.ctx, c.Name, types.ClusterConfigSpecEx{})
+	_, err = folder.CreateCluster(ctx, c.Name, types.ClusterConfigSpecEx{})
 
 	return err
 }
 
 // Delete removes the cluster.
 func (c *Cluster) Delete() error {
+	return c.DeleteContext(c.ctx)
+}
+
+// DeleteContext removes the cluster using the given context.
+func (c *Cluster) DeleteContext(ctx context.Context) error {
 	Logf("%s removing cluster\n", c.ID())
 
-	obj, err := c.finder.ClusterComputeResource(c.ctx, path.Join(c.Path, c.Name))
+	obj, err := c.finder.ClusterComputeResource(ctx, path.Join(c.Path, c.Name))
 	if err != nil {
 		return err
 	}
 
-	task, err := obj.Destroy(c.ctx)
+	task, err := obj.Destroy(ctx)
 	if err != nil {
 		return err
 	}
 
-	return task.Wait(c.ctx)
+	return task.Wait(ctx)
 }
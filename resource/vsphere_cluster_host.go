@@ -26,6 +26,7 @@
 package resource
 
 import (
+	"context"
 	"path"
 
 	"github.com/vmware/govmomi/find"
@@ -49,11 +50,11 @@ type ClusterHost struct {
 
 	// EsxiUsername is the username used to connect to the
 	// remote ESXi host. Defaults to an empty string.
-	EsxiUsername string `luar:"esxi_username"`
+	EsxiUsername string `luar:"esxi_username" spec:"secret"`
 
 	// EsxiPassword is the password used to connect to the
 	// remote ESXi host. Defaults to an empty string.
-	EsxiPassword string `luar:"esxi_password"`
+	EsxiPassword string `luar:"esxi_password" spec:"secret"`
 
 	// SSL thumbprint of the host. Defaults to an empty string.
 	SslThumbprint string `luar:"ssl_thumbprint"`
@@ -84,6 +85,9 @@ func NewClusterHost(name string) (Resource, error) {
 				AbsentStatesList:  []string{"absent"},
 				Concurrent:        true,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
 			Username: "",
 			Password: "",
@@ -104,12 +108,18 @@ func NewClusterHost(name string) (Resource, error) {
 
 // Evaluate evaluates the state of the host in the cluster.
 func (ch *ClusterHost) Evaluate() (State, error) {
+	return ch.EvaluateContext(ch.ctx)
+}
+
+// EvaluateContext evaluates the state of the host in the cluster
+// using the given context.
+func (ch *ClusterHost) EvaluateContext(ctx context.Context) (State, error) {
 	state := State{
 		Current: "unknown",
 		Want:    ch.State,
 	}
 
-	_, err := ch.finder.HostSystem(ch.ctx, path.Join(ch.Path, ch.Name))
+	_, err := ch.finder.HostSystem(ctx, path.Join(ch.Path, ch.Name))
 	if err != nil {
 		// Host is absent
 		if _, ok := err.(*find.NotFoundError); ok {
@@ -128,9 +138,14 @@ func (ch *ClusterHost) Evaluate() (State, error) {
 
 // Create adds the host to the cluster.
 func (ch *ClusterHost) Create() error {
+	return ch.CreateContext(ch.ctx)
+}
+
+// CreateContext adds the host to the cluster using the given context.
+func (ch *ClusterHost) CreateContext(ctx context.Context) error {
 	Logf("%s adding host to %s\n", ch.ID(), ch.Path)
 
-	obj, err := ch.finder.ClusterComputeResource(ch.ctx, ch.Path)
+	obj, err := ch.finder.ClusterComputeResource(ctx, ch.Path)
 	if err != nil {
 		return err
 	}
@@ -145,22 +160,28 @@ func (ch *ClusterHost) Create() error {
 		LockdownMode:  "",
 	}
 
-	task, err := obj.AddHost(ch.ctx, spec, true, &ch.License, nil)
+	task, err := obj.AddHost(ctx, spec, true, &ch.License, nil)
 	if err != nil {
 		return err
 	}
 
-	return task.Wait(ch.ctx)
+	return task.Wait(ctx)
 }
 
 // Delete disconnects the host and then removes it.
 func (ch *ClusterHost) Delete() error {
+	return ch.DeleteContext(ch.ctx)
+}
+
+// DeleteContext disconnects the host and then removes it
+// using the given context.
+func (ch *ClusterHost) DeleteContext(ctx context.Context) error {
 	Logf("%s removing host from %s\n", ch.ID(), ch.Path)
 
-	obj, err := ch.finder.HostSystem(ch.ctx, path.Join(ch.Path, ch.Name))
+	obj, err := ch.finder.HostSystem(ctx, path.Join(ch.Path, ch.Name))
 	if err != nil {
 		return err
 	}
 
-	return vSphereRemoveHost(ch.ctx, obj)
+	return vSphereRemoveHost(ctx, obj)
 }
@@ -25,7 +25,11 @@
 
 package resource
 
-import "github.com/vmware/govmomi/find"
+import (
+	"context"
+
+	"github.com/vmware/govmomi/find"
+)
 
 // Datacenter type is a resource which manages datacenters in a
 // VMware vSphere environment.
@@ -56,6 +60,9 @@ func NewDatacenter(name string) (Resource, error) {
 				AbsentStatesList:  []string{"absent"},
 				Concurrent:        true,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
 			Username: "",
 			Password: "",
@@ -70,12 +77,18 @@ func NewDatacenter(name string) (Resource, error) {
 
 // Evaluate evaluates the state of the datacenter.
 func (d *Datacenter) Evaluate() (State, error) {
+	return d.EvaluateContext(d.ctx)
+}
+
+// EvaluateContext evaluates the state of the datacenter
+// using the given context.
+func (d *Datacenter) EvaluateContext(ctx context.Context) (State, error) {
 	state := State{
 		Current: "unknown",
 		Want:    d.State,
 	}
 
-	_, err := d.finder.Datacenter(d.ctx, d.Name)
+	_, err := d.finder.Datacenter(ctx, d.Name)
 	if err != nil {
 		// Datacenter is absent
 		if _, ok := err.(*find.NotFoundError); ok {
@@ -94,31 +107,41 @@ func (d *Datacenter) Evaluate() (State, error) {
 
 // Create creates a new datacenter.
 func (d *Datacenter) Create() error {
+	return d.CreateContext(d.ctx)
+}
+
+// CreateContext creates a new datacenter using the given context.
+func (d *Datacenter) CreateContext(ctx context.Context) error {
 	Logf("%s creating datacenter in %s\n", d.ID(), d.Path)
 
-	folder, err := d.finder.FolderOrDefault(d.ctx, d.Path)
+	folder, err := d.finder.FolderOrDefault(ctx, d.Path)
 	if err != nil {
 		return err
 	}
 
-	_, err = folder.CreateDatacenter(d.ctx, d.Name)
+	_, err = folder.CreateDatacenter(ctx, d.Name)
 
 	return err
 }
 
 // Delete removes the datacenter.
 func (d *Datacenter) Delete() error {
+	return d.DeleteContext(d.ctx)
+}
+
+// DeleteContext removes the datacenter using the given context.
+func (d *Datacenter) DeleteContext(ctx context.Context) error {
 	Logf("%s removing datacenter from %s\n", d.ID(), d.Path)
 
-	dc, err := d.finder.Datacenter(d.ctx, d.Name)
+	dc, err := d.finder.Datacenter(ctx, d.Name)
 	if err != nil {
 		return err
 	}
 
-	task, err := dc.Destroy(d.ctx)
+	task, err := dc.Destroy(ctx)
 	if err != nil {
 		return err
 	}
 
-	return task.Wait(d.ctx)
+	return task.Wait(ctx)
 }
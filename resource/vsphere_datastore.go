@@ -26,6 +26,7 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"path"
 
@@ -79,15 +80,15 @@ type DatastoreNfs struct {
 }
 
 // mountOn mounts the NFS datastore on an ESXi host.
-func (ds *DatastoreNfs) mountOn(host string) error {
+func (ds *DatastoreNfs) mountOn(ctx context.Context, host string) error {
 	Logf("%s mounting datastore on %s\n", ds.ID(), path.Base(host))
 
-	obj, err := ds.finder.HostSystem(ds.ctx, host)
+	obj, err := ds.finder.HostSystem(ctx, host)
 	if err != nil {
 		return err
 	}
 
-	datastoreSystem, err := obj.ConfigManager().DatastoreSystem(ds.ctx)
+	datastoreSystem, err := obj.ConfigManager().DatastoreSystem(ctx)
 	if err != nil {
 		return err
 	}
@@ -100,7 +101,7 @@ func (ds *DatastoreNfs) mountOn(host string) error {
 		Type:       ds.NfsType,
 	}
 
-	_, err = datastoreSystem.CreateNasDatastore(ds.ctx, spec)
+	_, err = datastoreSystem.CreateNasDatastore(ctx, spec)
 
 	return err
 }
@@ -147,8 +148,14 @@ func (ds *DatastoreNfs) isHostsPropertySynced() (bool, error) {
 
 // setHostsProperty mounts the datastore on hosts which are out of date.
 func (ds *DatastoreNfs) setHostsProperty() error {
+	return ds.setHostsPropertyContext(ds.ctx)
+}
+
+// setHostsPropertyContext mounts the datastore on hosts which are out
+// of date using the given context.
+func (ds *DatastoreNfs) setHostsPropertyContext(ctx context.Context) error {
 	for _, host := range ds.shouldMountOnHosts {
-		if err := ds.mountOn(host); err != nil {
+		if err := ds.mountOn(ctx, host); err != nil {
 			return err
 		}
 	}
@@ -169,6 +176,9 @@ func NewDatastoreNfs(name string) (Resource, error) {
 				AbsentStatesList:  []string{"absent"},
 				Concurrent:        true,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
 		},
 		Hosts:     make([]string, 0),
@@ -181,9 +191,10 @@ func NewDatastoreNfs(name string) (Resource, error) {
 	// Set resource properties
 	ds.PropertyList = []Property{
 		&ResourceProperty{
-			PropertyName:         "attached-hosts",
-			PropertyIsSyncedFunc: ds.isHostsPropertySynced,
-			PropertySetFunc:      ds.setHostsProperty,
+			PropertyName:           "attached-hosts",
+			PropertyIsSyncedFunc:   ds.isHostsPropertySynced,
+			PropertySetFunc:        ds.setHostsProperty,
+			PropertySetContextFunc: ds.setHostsPropertyContext,
 		},
 	}
 
@@ -205,12 +216,18 @@ func (ds *DatastoreNfs) Validate() error {
 
 // Evaluate evaluates the state of the datastore.
 func (ds *DatastoreNfs) Evaluate() (State, error) {
+	return ds.EvaluateContext(ds.ctx)
+}
+
+// EvaluateContext evaluates the state of the datastore
+// using the given context.
+func (ds *DatastoreNfs) EvaluateContext(ctx context.Context) (State, error) {
 	state := State{
 		Current: "unknown",
 		Want:    ds.State,
 	}
 
-	_, err := ds.finder.Datastore(ds.ctx, path.Join(ds.Path, ds.Name))
+	_, err := ds.finder.Datastore(ctx, path.Join(ds.Path, ds.Name))
 	if err != nil {
 		// Datastore is absent
 		if _, ok := err.(*find.NotFoundError); ok {
@@ -229,6 +246,12 @@ func (ds *DatastoreNfs) Evaluate() (State, error) {
 
 // Create mounts the NFS datastore on the ESXi hosts.
 func (ds *DatastoreNfs) Create() error {
+	return ds.CreateContext(ds.ctx)
+}
+
+// CreateContext mounts the NFS datastore on the ESXi hosts
+// using the given context.
+func (ds *DatastoreNfs) CreateContext(ctx context.Context) error {
 	if ds.NfsServer == "" {
 		return errors.New("Missing NFS server for datastore")
 	}
@@ -238,7 +261,7 @@ func (ds *DatastoreNfs) Create() error {
 	}
 
 	for _, host := range ds.Hosts {
-		if err := ds.mountOn(host); err != nil {
+		if err := ds.mountOn(ctx, host); err != nil {
 			return err
 		}
 	}
@@ -248,24 +271,30 @@ func (ds *DatastoreNfs) Create() error {
 
 // Delete unmounts the NFS datastore from the ESXi hosts.
 func (ds *DatastoreNfs) Delete() error {
-	datastore, err := ds.finder.Datastore(ds.ctx, path.Join(ds.Path, ds.Name))
+	return ds.DeleteContext(ds.ctx)
+}
+
+// DeleteContext unmounts the NFS datastore from the ESXi hosts
+// using the given context.
+func (ds *DatastoreNfs) DeleteContext(ctx context.Context) error {
+	datastore, err := ds.finder.Datastore(ctx, path.Join(ds.Path, ds.Name))
 	if err != nil {
 		return err
 	}
 
 	for _, host := range ds.Hosts {
 		Logf("%s unmounting datastore from %s\n", ds.ID(), path.Base(host))
-		obj, err := ds.finder.HostSystem(ds.ctx, host)
+		obj, err := ds.finder.HostSystem(ctx, host)
 		if err != nil {
 			return err
 		}
 
-		datastoreSystem, err := obj.ConfigManager().DatastoreSystem(ds.ctx)
+		datastoreSystem, err := obj.ConfigManager().DatastoreSystem(ctx)
 		if err != nil {
 			return err
 		}
 
-		if err := datastoreSystem.Remove(ds.ctx, datastore); err != nil {
+		if err := datastoreSystem.Remove(ctx, datastore); err != nil {
 			return err
 		}
 	}
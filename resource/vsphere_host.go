@@ -26,6 +26,7 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"path"
 	"reflect"
@@ -92,15 +93,15 @@ type Host struct {
 
 // hostProperties is a helper which retrieves properties for the
 // ESXi host managed by the resource.
-func (h *Host) hostProperties(ps []string) (mo.HostSystem, error) {
+func (h *Host) hostProperties(ctx context.Context, ps []string) (mo.HostSystem, error) {
 	var host mo.HostSystem
 
-	obj, err := h.finder.HostSystem(h.ctx, path.Join(h.Path, h.Name))
+	obj, err := h.finder.HostSystem(ctx, path.Join(h.Path, h.Name))
 	if err != nil {
 		return host, err
 	}
 
-	if err := obj.Properties(h.ctx, obj.Reference(), ps, &host); err != nil {
+	if err := obj.Properties(ctx, obj.Reference(), ps, &host); err != nil {
 		return host, err
 	}
 
@@ -115,7 +116,7 @@ func (h *Host) isDnsConfigSynced() (bool, error) {
 		return true, nil
 	}
 
-	host, err := h.hostProperties([]string{"config"})
+	host, err := h.hostProperties(h.ctx, []string{"config"})
 	if err != nil {
 		if _, ok := err.(*find.NotFoundError); ok {
 			return false, ErrResourceAbsent
@@ -153,14 +154,20 @@ func (h *Host) isDnsConfigSynced() (bool, error) {
 
 // setDnsConfig configures the DNS settings on the ESXi host.
 func (h *Host) setDnsConfig() error {
+	return h.setDnsConfigContext(h.ctx)
+}
+
+// setDnsConfigContext configures the DNS settings on the ESXi host
+// using the given context.
+func (h *Host) setDnsConfigContext(ctx context.Context) error {
 	Logf("%s configuring dns settings\n", h.ID())
 
-	obj, err := h.finder.HostSystem(h.ctx, path.Join(h.Path, h.Name))
+	obj, err := h.finder.HostSystem(ctx, path.Join(h.Path, h.Name))
 	if err != nil {
 		return err
 	}
 
-	networkSystem, err := obj.ConfigManager().NetworkSystem(h.ctx)
+	networkSystem, err := obj.ConfigManager().NetworkSystem(ctx)
 	if err != nil {
 		return err
 	}
@@ -173,7 +180,7 @@ func (h *Host) setDnsConfig() error {
 		SearchDomain: h.Dns.Search,
 	}
 
-	return networkSystem.UpdateDnsConfig(h.ctx, config)
+	return networkSystem.UpdateDnsConfig(ctx, config)
 }
 
 // isLockdownSynced checks if the lockdown mode of the
@@ -184,7 +191,7 @@ func (h *Host) isLockdownSynced() (bool, error) {
 		return true, nil
 	}
 
-	host, err := h.hostProperties([]string{"config"})
+	host, err := h.hostProperties(h.ctx, []string{"config"})
 	if err != nil {
 		if _, ok := err.(*find.NotFoundError); ok {
 			return false, ErrResourceAbsent
@@ -197,6 +204,12 @@ func (h *Host) isLockdownSynced() (bool, error) {
 // setLockdown sets the lockdown mode for the ESXi host.
 // This feature is available only for ESXi 6.0 or above.
 func (h *Host) setLockdown() error {
+	return h.setLockdownContext(h.ctx)
+}
+
+// setLockdownContext sets the lockdown mode for the ESXi host
+// using the given context.
+func (h *Host) setLockdownContext(ctx context.Context) error {
 	// Setting lockdown mode is supported starting from vSphere API 6.0
 	// Ensure that the ESXi host is at least at version 6.0.0
 	minVersion, err := semver.Make("6.0.0")
@@ -204,12 +217,12 @@ func (h *Host) setLockdown() error {
 		return err
 	}
 
-	obj, err := h.finder.HostSystem(h.ctx, path.Join(h.Path, h.Name))
+	obj, err := h.finder.HostSystem(ctx, path.Join(h.Path, h.Name))
 	if err != nil {
 		return err
 	}
 
-	host, err := h.hostProperties([]string{"config", "configManager"})
+	host, err := h.hostProperties(ctx, []string{"config", "configManager"})
 	if err != nil {
 		return err
 	}
@@ -226,7 +239,7 @@ func (h *Host) setLockdown() error {
 	Logf("%s setting lockdown mode to %s\n", h.ID(), h.LockdownMode)
 
 	var accessManager mo.HostAccessManager
-	if err := obj.Properties(h.ctx, *host.ConfigManager.HostAccessManager, nil, &accessManager); err != nil {
+	if err := obj.Properties(ctx, *host.ConfigManager.HostAccessManager, nil, &accessManager); err != nil {
 		return err
 	}
 
@@ -235,7 +248,7 @@ func (h *Host) setLockdown() error {
 		Mode: h.LockdownMode,
 	}
 
-	_, err = methods.ChangeLockdownMode(h.ctx, h.client, req)
+	_, err = methods.ChangeLockdownMode(ctx, h.client, req)
 
 	return err
 }
@@ -253,6 +266,9 @@ func NewHost(name string) (Resource, error) {
 				AbsentStatesList:  []string{"absent"},
 				Concurrent:        true,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
 			Username: "",
 			Password: "",
@@ -267,14 +283,16 @@ func NewHost(name string) (Resource, error) {
 	// Set resource properties
 	h.PropertyList = []Property{
 		&ResourceProperty{
-			PropertyName:         "dns-config",
-			PropertySetFunc:      h.setDnsConfig,
-			PropertyIsSyncedFunc: h.isDnsConfigSynced,
+			PropertyName:           "dns-config",
+			PropertySetFunc:        h.setDnsConfig,
+			PropertySetContextFunc: h.setDnsConfigContext,
+			PropertyIsSyncedFunc:   h.isDnsConfigSynced,
 		},
 		&ResourceProperty{
-			PropertyName:         "lockdown-mode",
-			PropertySetFunc:      h.setLockdown,
-			PropertyIsSyncedFunc: h.isLockdownSynced,
+			PropertyName:           "lockdown-mode",
+			PropertySetFunc:        h.setLockdown,
+			PropertySetContextFunc: h.setLockdownContext,
+			PropertyIsSyncedFunc:   h.isLockdownSynced,
 		},
 	}
 
@@ -283,12 +301,18 @@ func NewHost(name string) (Resource, error) {
 
 // Evaluate evaluate the state of the ESXi host.
 func (h *Host) Evaluate() (State, error) {
+	return h.EvaluateContext(h.ctx)
+}
+
+// EvaluateContext evaluates the state of the ESXi host
+// using the given context.
+func (h *Host) EvaluateContext(ctx context.Context) (State, error) {
 	state := State{
 		Current: "unknown",
 		Want:    h.State,
 	}
 
-	_, err := h.finder.HostSystem(h.ctx, path.Join(h.Path, h.Name))
+	_, err := h.finder.HostSystem(ctx, path.Join(h.Path, h.Name))
 	if err != nil {
 		// Host is absent
 		if _, ok := err.(*find.NotFoundError); ok {
@@ -311,14 +335,25 @@ func (h *Host) Create() error {
 	return nil
 }
 
+// CreateContext is a no-op.
+func (h *Host) CreateContext(ctx context.Context) error {
+	return nil
+}
+
 // Delete disconnects the host and then removes it.
 func (h *Host) Delete() error {
+	return h.DeleteContext(h.ctx)
+}
+
+// DeleteContext disconnects the host and then removes it
+// using the given context.
+func (h *Host) DeleteContext(ctx context.Context) error {
 	Logf("%s removing host from %s\n", h.ID(), h.Path)
 
-	obj, err := h.finder.HostSystem(h.ctx, path.Join(h.Path, h.Name))
+	obj, err := h.finder.HostSystem(ctx, path.Join(h.Path, h.Name))
 	if err != nil {
 		return err
 	}
 
-	return vSphereRemoveHost(h.ctx, obj)
+	return vSphereRemoveHost(ctx, obj)
 }
@@ -241,9 +241,15 @@ func (vm *VirtualMachine) isVmHardwareSynced() (bool, error) {
 
 // setVmHardware configures the virtual machine hardware.
 func (vm *VirtualMachine) setVmHardware() error {
+	return vm.setVmHardwareContext(vm.ctx)
+}
+
+// setVmHardwareContext configures the virtual machine hardware
+// using the given context.
+func (vm *VirtualMachine) setVmHardwareContext(ctx context.Context) error {
 	Logf("%s configuring hardware\n", vm.ID())
 
-	obj, err := vm.finder.VirtualMachine(vm.ctx, path.Join(vm.Path, vm.Name))
+	obj, err := vm.finder.VirtualMachine(ctx, path.Join(vm.Path, vm.Name))
 	if err != nil {
 		return err
 	}
@@ -254,12 +260,12 @@ func (vm *VirtualMachine) setVmHardware() error {
 		MemoryMB:          vm.Hardware.Memory,
 	}
 
-	task, err := obj.Reconfigure(vm.ctx, spec)
+	task, err := obj.Reconfigure(ctx, spec)
 	if err != nil {
 		return err
 	}
 
-	return task.Wait(vm.ctx)
+	return task.Wait(ctx)
 }
 
 // isVmExtraConfigSynced checks if the extra settings are in sync.
@@ -294,9 +300,15 @@ func (vm *VirtualMachine) isVmExtraConfigSynced() (bool, error) {
 
 // setVmExtraConfig configures extra settings of the virtual machine.
 func (vm *VirtualMachine) setVmExtraConfig() error {
+	return vm.setVmExtraConfigContext(vm.ctx)
+}
+
+// setVmExtraConfigContext configures extra settings of the virtual
+// machine using the given context.
+func (vm *VirtualMachine) setVmExtraConfigContext(ctx context.Context) error {
 	Logf("%s configuring extra settings\n", vm.ID())
 
-	obj, err := vm.finder.VirtualMachine(vm.ctx, path.Join(vm.Path, vm.Name))
+	obj, err := vm.finder.VirtualMachine(ctx, path.Join(vm.Path, vm.Name))
 	if err != nil {
 		return err
 	}
@@ -307,12 +319,12 @@ func (vm *VirtualMachine) setVmExtraConfig() error {
 		MemoryHotAddEnabled: &vm.ExtraConfig.MemoryHotAdd,
 	}
 
-	task, err := obj.Reconfigure(vm.ctx, spec)
+	task, err := obj.Reconfigure(ctx, spec)
 	if err != nil {
 		return err
 	}
 
-	return task.Wait(vm.ctx)
+	return task.Wait(ctx)
 
 }
 
@@ -336,9 +348,15 @@ func (vm *VirtualMachine) isVmAnnotationSynced() (bool, error) {
 
 // setVmAnnotation sets the annotation property of the virtual machine.
 func (vm *VirtualMachine) setVmAnnotation() error {
+	return vm.setVmAnnotationContext(vm.ctx)
+}
+
+// setVmAnnotationContext sets the annotation property of the
+// virtual machine using the given context.
+func (vm *VirtualMachine) setVmAnnotationContext(ctx context.Context) error {
 	Logf("%s setting annotation\n", vm.ID())
 
-	obj, err := vm.finder.VirtualMachine(vm.ctx, path.Join(vm.Path, vm.Name))
+	obj, err := vm.finder.VirtualMachine(ctx, path.Join(vm.Path, vm.Name))
 	if err != nil {
 		return err
 	}
@@ -347,12 +365,12 @@ func (vm *VirtualMachine) setVmAnnotation() error {
 		Annotation: vm.Annotation,
 	}
 
-	task, err := obj.Reconfigure(vm.ctx, spec)
+	task, err := obj.Reconfigure(ctx, spec)
 	if err != nil {
 		return err
 	}
 
-	return task.Wait(vm.ctx)
+	return task.Wait(ctx)
 
 }
 
@@ -383,9 +401,15 @@ func (vm *VirtualMachine) isVmPowerStateSynced() (bool, error) {
 // setVmPowerState sets the power state of the virtual machine in the
 // desired state.
 func (vm *VirtualMachine) setVmPowerState() error {
+	return vm.setVmPowerStateContext(vm.ctx)
+}
+
+// setVmPowerStateContext sets the power state of the virtual machine
+// in the desired state using the given context.
+func (vm *VirtualMachine) setVmPowerStateContext(ctx context.Context) error {
 	Logf("%s setting power state to %s\n", vm.ID(), vm.PowerState)
 
-	obj, err := vm.finder.VirtualMachine(vm.ctx, path.Join(vm.Path, vm.Name))
+	obj, err := vm.finder.VirtualMachine(ctx, path.Join(vm.Path, vm.Name))
 	if err != nil {
 		return err
 	}
@@ -402,18 +426,18 @@ func (vm *VirtualMachine) setVmPowerState() error {
 		return errors.New("Invalid virtual machine power state")
 	}
 
-	task, err := operation(vm.ctx)
+	task, err := operation(ctx)
 	if err != nil {
 		return err
 	}
 
-	if err := task.Wait(vm.ctx); err != nil {
+	if err := task.Wait(ctx); err != nil {
 		return err
 	}
 
 	if vm.WaitForIP && vm.PowerState == types.VirtualMachinePowerStatePoweredOn {
 		Logf("%s waiting for IP address\n", vm.ID())
-		ip, err := obj.WaitForIP(vm.ctx)
+		ip, err := obj.WaitForIP(ctx)
 		if err != nil {
 			return err
 		}
@@ -437,6 +461,9 @@ func NewVirtualMachine(name string) (Resource, error) {
 				AbsentStatesList:  []string{"absent"},
 				Concurrent:        true,
 				Subscribe:         make(TriggerMap),
+				OnFailure:         make(TriggerMap),
+				OnSuccess:         make(TriggerMap),
+				OnUnchanged:       make(TriggerMap),
 			},
 			Username: "",
 			Password: "",
@@ -458,24 +485,28 @@ func NewVirtualMachine(name string) (Resource, error) {
 
 	vm.PropertyList = []Property{
 		&ResourceProperty{
-			PropertyName:         "hardware",
-			PropertySetFunc:      vm.setVmHardware,
-			PropertyIsSyncedFunc: vm.isVmHardwareSynced,
+			PropertyName:           "hardware",
+			PropertySetFunc:        vm.setVmHardware,
+			PropertySetContextFunc: vm.setVmHardwareContext,
+			PropertyIsSyncedFunc:   vm.isVmHardwareSynced,
 		},
 		&ResourceProperty{
-			PropertyName:         "extra-config",
-			PropertySetFunc:      vm.setVmExtraConfig,
-			PropertyIsSyncedFunc: vm.isVmExtraConfigSynced,
+			PropertyName:           "extra-config",
+			PropertySetFunc:        vm.setVmExtraConfig,
+			PropertySetContextFunc: vm.setVmExtraConfigContext,
+			PropertyIsSyncedFunc:   vm.isVmExtraConfigSynced,
 		},
 		&ResourceProperty{
-			PropertyName:         "annotation",
-			PropertySetFunc:      vm.setVmAnnotation,
-			PropertyIsSyncedFunc: vm.isVmAnnotationSynced,
+			PropertyName:           "annotation",
+			PropertySetFunc:        vm.setVmAnnotation,
+			PropertySetContextFunc: vm.setVmAnnotationContext,
+			PropertyIsSyncedFunc:   vm.isVmAnnotationSynced,
 		},
 		&ResourceProperty{
-			PropertyName:         "power-state",
-			PropertySetFunc:      vm.setVmPowerState,
-			PropertyIsSyncedFunc: vm.isVmPowerStateSynced,
+			PropertyName:           "power-state",
+			PropertySetFunc:        vm.setVmPowerState,
+			PropertySetContextFunc: vm.setVmPowerStateContext,
+			PropertyIsSyncedFunc:   vm.isVmPowerStateSynced,
 		},
 	}
 
@@ -509,12 +540,18 @@ func (vm *VirtualMachine) Validate() error {
 
 // Evaluate evaluates the state of the virtual machine.
 func (vm *VirtualMachine) Evaluate() (State, error) {
+	return vm.EvaluateContext(vm.ctx)
+}
+
+// EvaluateContext evaluates the state of the virtual machine
+// using the given context.
+func (vm *VirtualMachine) EvaluateContext(ctx context.Context) (State, error) {
 	state := State{
 		Current: "unknown",
 		Want:    vm.State,
 	}
 
-	_, err := vm.finder.VirtualMachine(vm.ctx, path.Join(vm.Path, vm.Name))
+	_, err := vm.finder.VirtualMachine(ctx, path.Join(vm.Path, vm.Name))
 	if err != nil {
 		// Virtual Machine is absent
 		if _, ok := err.(*find.NotFoundError); ok {
@@ -532,7 +569,7 @@ func (vm *VirtualMachine) Evaluate() (State, error) {
 }
 
 // newVm creates a new virtual machine.
-func (vm *VirtualMachine) newVm(f *object.Folder, p *object.ResourcePool, ds *object.Datastore, h *object.HostSystem) error {
+func (vm *VirtualMachine) newVm(ctx context.Context, f *object.Folder, p *object.ResourcePool, ds *object.Datastore, h *object.HostSystem) error {
 	if vm.Hardware == nil {
 		return errors.New("Missing hardware configuration")
 	}
@@ -553,19 +590,19 @@ func (vm *VirtualMachine) newVm(f *object.Folder, p *object.ResourcePool, ds *ob
 		},
 	}
 
-	task, err := f.CreateVM(vm.ctx, spec, p, h)
+	task, err := f.CreateVM(ctx, spec, p, h)
 	if err != nil {
 		return err
 	}
 
-	return task.Wait(vm.ctx)
+	return task.Wait(ctx)
 }
 
 // cloneVm creates the virtual machine using a template.
-func (vm *VirtualMachine) cloneVm(f *object.Folder, p *object.ResourcePool, ds *object.Datastore, h *object.HostSystem) error {
+func (vm *VirtualMachine) cloneVm(ctx context.Context, f *object.Folder, p *object.ResourcePool, ds *object.Datastore, h *object.HostSystem) error {
 	Logf("%s cloning virtual machine from %s\n", vm.ID(), vm.TemplateConfig.Use)
 
-	obj, err := vm.finder.VirtualMachine(vm.ctx, vm.TemplateConfig.Use)
+	obj, err := vm.finder.VirtualMachine(ctx, vm.TemplateConfig.Use)
 	if err != nil {
 		return err
 	}
@@ -591,34 +628,39 @@ func (vm *VirtualMachine) cloneVm(f *object.Folder, p *object.ResourcePool, ds *
 		PowerOn:  vm.TemplateConfig.PowerOn,
 	}
 
-	task, err := obj.Clone(vm.ctx, f, vm.Name, spec)
+	task, err := obj.Clone(ctx, f, vm.Name, spec)
 	if err != nil {
 		return err
 	}
 
-	return task.Wait(vm.ctx)
+	return task.Wait(ctx)
 }
 
 // Create creates the virtual machine.
 func (vm *VirtualMachine) Create() error {
-	folder, err := vm.finder.Folder(vm.ctx, vm.Path)
+	return vm.CreateContext(vm.ctx)
+}
+
+// CreateContext creates the virtual machine using the given context.
+func (vm *VirtualMachine) CreateContext(ctx context.Context) error {
+	folder, err := vm.finder.Folder(ctx, vm.Path)
 	if err != nil {
 		return err
 	}
 
-	pool, err := vm.finder.ResourcePool(vm.ctx, vm.Pool)
+	pool, err := vm.finder.ResourcePool(ctx, vm.Pool)
 	if err != nil {
 		return err
 	}
 
-	datastore, err := vm.finder.Datastore(vm.ctx, vm.Datastore)
+	datastore, err := vm.finder.Datastore(ctx, vm.Datastore)
 	if err != nil {
 		return err
 	}
 
 	var host *object.HostSystem
 	if vm.Host != "" {
-		host, err = vm.finder.HostSystem(vm.ctx, vm.Host)
+		host, err = vm.finder.HostSystem(ctx, vm.Host)
 		if err != nil {
 			return err
 		}
@@ -626,23 +668,28 @@ func (vm *VirtualMachine) Create() error {
 
 	// If we have a template config, clone the virtual machine
 	if vm.TemplateConfig != nil {
-		return vm.cloneVm(folder, pool, datastore, host)
+		return vm.cloneVm(ctx, folder, pool, datastore, host)
 	}
 
 	// Otherwise create a new virtual machine
-	return vm.newVm(folder, pool, datastore, host)
+	return vm.newVm(ctx, folder, pool, datastore, host)
 }
 
 // Delete removes the virtual machine.
 func (vm *VirtualMachine) Delete() error {
+	return vm.DeleteContext(vm.ctx)
+}
+
+// DeleteContext removes the virtual machine using the given context.
+func (vm *VirtualMachine) DeleteContext(ctx context.Context) error {
 	Logf("%s removing virtual machine\n", vm.ID())
 
-	obj, err := vm.finder.VirtualMachine(vm.ctx, path.Join(vm.Path, vm.Name))
+	obj, err := vm.finder.VirtualMachine(ctx, path.Join(vm.Path, vm.Name))
 	if err != nil {
 		return err
 	}
 
-	powerState, err := obj.PowerState(vm.ctx)
+	powerState, err := obj.PowerState(ctx)
 	if err != nil {
 		return err
 	}
@@ -650,19 +697,19 @@ func (vm *VirtualMachine) Delete() error {
 	// Power off the virtual machine if it is not already
 	if powerState != types.VirtualMachinePowerStatePoweredOff {
 		Logf("%s powering off virtual machine\n", vm.ID())
-		task, err := obj.PowerOff(vm.ctx)
+		task, err := obj.PowerOff(ctx)
 		if err != nil {
 			return err
 		}
-		if err := task.Wait(vm.ctx); err != nil {
+		if err := task.Wait(ctx); err != nil {
 			return err
 		}
 	}
 
-	task, err := obj.Destroy(vm.ctx)
+	task, err := obj.Destroy(ctx)
 	if err != nil {
 		return err
 	}
 
-	return task.Wait(vm.ctx)
+	return task.Wait(ctx)
 }
@@ -25,6 +25,11 @@ unit_file.require = {
    unit_dir:ID(),
 }
 
+-- Restart the memcached service if the drop-in unit has changed
+unit_file.notify = {
+   "service[memcached]",
+}
+
 -- Instruct systemd(1) to reload it's configuration
 systemd_reload = resource.shell.new("systemctl daemon-reload")
 systemd_reload.require = {
@@ -38,6 +43,7 @@ svc.enable = true
 svc.require = {
    pkg:ID(),
    unit_file:ID(),
+   systemd_reload:ID(),
 }
 
 -- Finally, register the resources to the catalog
@@ -0,0 +1,27 @@
+--
+-- Example code for refreshing resources using notifications
+--
+
+-- Manage the SNMP package
+pkg = resource.package.new("net-snmp")
+pkg.state = "present"
+
+-- Manage the SNMP service
+svc = resource.service.new("snmpd")
+svc.state = "running"
+svc.enable = true
+svc.require = { pkg:ID() }
+
+-- Reload the service instead of restarting it, when refreshed
+svc.reload = true
+
+-- Manage the config file for SNMP daemon.
+-- Refresh the SNMP daemon service if the config file has changed.
+config = resource.file.new("/etc/snmp/snmpd.conf")
+config.state = "present"
+config.content = "rocommunity public"
+config.require = { pkg:ID() }
+config.notify = { svc:ID() }
+
+-- Add resources to the catalog
+catalog:add(pkg, config, svc)
@@ -0,0 +1,19 @@
+--
+-- Example code for installing a package from a third-party repository
+--
+
+-- Manage the EPEL repository and it's signing key.
+-- The signing key is verified against the given fingerprint.
+repo = resource.yum_repo.new("epel")
+repo.description = "Extra Packages for Enterprise Linux 7"
+repo.mirrorlist = "https://mirrors.fedoraproject.org/metalink?repo=epel-7&arch=$basearch"
+repo.key = "https://dl.fedoraproject.org/pub/epel/RPM-GPG-KEY-EPEL-7"
+repo.key_fingerprint = "91E9 7D7C 4A5E 96F1 7F3E 888F 6A2F AEA2 352C 64E5"
+
+-- Manage a package from the EPEL repository
+pkg = resource.package.new("htop")
+pkg.state = "present"
+pkg.require = { repo:ID() }
+
+-- Add resources to the catalog
+catalog:add(repo, pkg)
@@ -19,9 +19,20 @@ svc.enable = true
 svc.require = { pkg:ID(), config:ID() }
 
 -- Subscribe for changes in the config file resource.
--- Reload the SNMP daemon service if the config file has changed.
-svc.subscribe[config:ID()] = function()
-   os.execute("systemctl reload snmpd")
+-- Reload the SNMP daemon service if the config file content has
+-- changed and restart it if the config file has been created.
+-- Triggers receive a table describing the change.
+svc.subscribe[config:ID()] = function(change)
+   if change.transition == "create" then
+      os.execute("systemctl restart snmpd")
+      return
+   end
+
+   for _, name in ipairs(change.properties) do
+      if name == "content" then
+         os.execute("systemctl reload snmpd")
+      end
+   end
 end
 
 -- Subscribe for changes in the package resource.
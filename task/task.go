@@ -25,7 +25,11 @@
 
 package task
 
-import "github.com/pborman/uuid"
+import (
+	"encoding/json"
+
+	"github.com/pborman/uuid"
+)
 
 // Task states
 const (
@@ -64,6 +68,18 @@ type Task struct {
 	// Command to be processed
 	Command string `json:"command"`
 
+	// Tags specifies that only resources with any of the
+	// given tags and their dependencies are processed
+	Tags []string `json:"tags,omitempty"`
+
+	// SkipTags specifies that resources with any of
+	// the given tags are not processed
+	SkipTags []string `json:"skipTags,omitempty"`
+
+	// Only contains the ids of the resources, which are
+	// processed along with their dependencies
+	Only []string `json:"only,omitempty"`
+
 	// Time when the command was sent for processing
 	TimeReceived int64 `json:"timeReceived"`
 
@@ -76,6 +92,9 @@ type Task struct {
 	// Result of task after processing
 	Result string `json:"result"`
 
+	// Report contains the JSON report of the processed catalog
+	Report json.RawMessage `json:"report,omitempty"`
+
 	// Task state
 	State string `json:"state"`
 }